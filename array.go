@@ -2,7 +2,10 @@ package govtk
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
 )
 
 // DataArray represents the inner data containers of the VTK XML structure.
@@ -13,6 +16,10 @@ type dataArray struct {
 	// Name of the XML element, e.g. PointData, CellData, etc.
 	XMLName xml.Name
 
+	// Attributes of point and cell data, referring to the arrays that
+	// have a specific meaning, see attributes.
+	attributes
+
 	// A collection of data sets within this XML element.
 	Data []*darray
 
@@ -37,6 +44,29 @@ type dataArray struct {
 	// The compressor holds an compressor interface, which allows to
 	// compress the provided data before writing to Binary or Raw formats.
 	compressor compressor
+
+	// header64 is true when the payload headers are written as UInt64.
+	header64 bool
+
+	// promote is called when a payload header does not fit UInt32. As the
+	// header type applies to the whole file, it promotes all arrays of the
+	// file towards UInt64 headers.
+	promote func() error
+
+	// streaming is true when the appended data is encoded while writing.
+	streaming bool
+
+	// leadingZero is true when the offsets of cells include a leading zero,
+	// as required by format version 2, see FormatVersion().
+	leadingZero bool
+
+	// idType64 is true when ids, e.g. the connectivity, are written as
+	// Int64 instead of Int32, see IDType64().
+	idType64 bool
+
+	// ranges refers to the header's setting to compute the range of each
+	// array, which is read when each array is added, see DataRange().
+	ranges *bool
 }
 
 // NewdataArray returns a newly allocated dataArray with encoder, compressor,
@@ -58,6 +88,19 @@ func newDataArray(enc encoder, cmp compressor, fieldData bool, app *darray) *dat
 	}
 }
 
+// attributes mark arrays of the point or cell data by name for a specific
+// use, e.g. the active vectors or the degrees of higher order cells. Unset
+// attributes are omitted.
+type attributes struct {
+	Scalars            string `xml:"Scalars,attr,omitempty"`
+	Vectors            string `xml:"Vectors,attr,omitempty"`
+	Normals            string `xml:"Normals,attr,omitempty"`
+	Tensors            string `xml:"Tensors,attr,omitempty"`
+	TCoords            string `xml:"TCoords,attr,omitempty"`
+	HigherOrderDegrees string `xml:"HigherOrderDegrees,attr,omitempty"`
+	RationalWeights    string `xml:"RationalWeights,attr,omitempty"`
+}
+
 // darray represent the innermost dataArray element containing various \
 // properties of the data, and the data itself.
 type darray struct {
@@ -72,6 +115,19 @@ type darray struct {
 	NumberOfComponents int `xml:"NumberOfComponents,attr,omitempty"`
 	NumberOfTuples     int `xml:"NumberOfTuples,attr,omitempty"`
 
+	// RangeMin and RangeMax hold the range of the values, or the range of
+	// the magnitude of the tuples for multiple components, see DataRange().
+	RangeMin *float64 `xml:"RangeMin,attr,omitempty"`
+	RangeMax *float64 `xml:"RangeMax,attr,omitempty"`
+
+	// Attrs holds additional attributes, e.g. ComponentName0, see
+	// ComponentNames().
+	Attrs []xml.Attr `xml:",any,attr"`
+
+	// Info holds the information keys of the array, which precede the
+	// data of inline arrays, see InformationKey().
+	Info []infoKey `xml:"InformationKey"`
+
 	// The actual data to be stored, always represent as a set of bytes
 	Data []byte `xml:",innerxml"`
 
@@ -83,6 +139,20 @@ type darray struct {
 	// consider Offset = 0 as an empty value. Thus, by making this a
 	// pointer, the xml encoding only considers it empty when equal to nil.
 	Offset *int `xml:"offset,attr,omitempty"`
+
+	// values holds the data of streamed arrays as provided to add, which
+	// is only encoded while writing, see Streaming(). Any other array only
+	// holds its encoded bytes, which are decoded on demand, see decode.
+	values interface{}
+
+	// size holds the length of the encoded bytes of an appended array.
+	size int
+
+	// streams holds the arrays of the appended data that are encoded while
+	// writing, in order of their offsets, where streamed holds their total
+	// encoded size. See Streaming().
+	streams  []*stream
+	streamed int
 }
 
 // Newdarray provides a new darray with properties set except the data fields
@@ -95,85 +165,184 @@ func newDArray(xmlName, dtype, name, format string) *darray {
 	}
 }
 
-// dataType tries to extract the data type, e.g. uint32, float64, etc., from
-// the emtpy interface.
-//
-// TODO: compare to XML VTK requirements
+// dataType tries to extract the data type, e.g. UInt32, Float64, etc., from
+// the emtpy interface. The Go types map directly onto the VTK types of equal
+// size and signedness. As int and uint are written as 64 bit values, see
+// fixedSize, these map onto Int64 and UInt64.
 func (da *dataArray) dataType(data interface{}) (string, error) {
 	switch data.(type) {
-	case int, int32, uint32, []int, []int32, []uint32:
+	case int8, []int8:
+		return "Int8", nil
+	case uint8, []uint8:
+		return "UInt8", nil
+	case int16, []int16:
+		return "Int16", nil
+	case uint16, []uint16:
+		return "UInt16", nil
+	case int32, []int32:
+		return "Int32", nil
+	case uint32, []uint32:
 		return "UInt32", nil
-	case int64, uint64, []int64, []uint64:
+	case int, int64, []int, []int64:
+		return "Int64", nil
+	case uint, uint64, []uint, []uint64:
 		return "UInt64", nil
-	case float64, []float64:
-		return "Float64", nil
 	case float32, []float32:
 		return "Float32", nil
+	case float64, []float64:
+		return "Float64", nil
 	}
 
-	// todo add err test
-	return "", fmt.Errorf("Cannot map data %v (%T) to type", data, data)
+	msg := "%w: cannot map data %v (%T) to type"
+	return "", fmt.Errorf(msg, ErrUnsupportedType, data, data)
 }
 
 // Add adds data to the data array. The data can be stored inline or
-// appended to a single storage
-func (da *dataArray) add(name string, n int, data interface{}) error {
+// appended to a single storage. The array options are applied to the array
+// before it is added.
+func (da *dataArray) add(name string, n int, data interface{},
+	opts ...ArrayOption) error {
 	// ensure no duplicate fields are present
 	if da.contains(name) {
-		msg := "Array already contains field '%s' in fields: %q"
-		return fmt.Errorf(msg, name, da.fieldNames())
-	}
-
-	// encode data into payload
-	if da.encoder == nil {
-		return fmt.Errorf("Missing encoder. No format specified.")
+		msg := "%w: array already contains field '%s' in fields: %q"
+		return fmt.Errorf(msg, ErrDuplicateName, name, da.fieldNames())
 	}
-	payload := da.encoder.binarise(data)
 
-	// compress payload
-	payload, err := da.compressor.compress(payload)
+	// extract data type to match XML VTK
+	dtype, err := da.dataType(data)
 	if err != nil {
 		return err
 	}
 
-	// encode payload as []byte
-	bytes, err := da.encoder.encode(payload)
+	// encode data, promote the header type when the payload requires so
+	bytes, s, err := da.encode(data)
+	if errors.Is(err, errHeaderOverflow) && !da.header64 && da.promote != nil {
+		if err := da.promote(); err != nil {
+			return err
+		}
+		da.header64 = true
+		bytes, s, err = da.encode(data)
+	}
 	if err != nil {
 		return err
 	}
 
-	// extract data type to match XML VTK
-	dtype, err := da.dataType(data)
-	if err != nil {
+	// get a new data array
+	arr := newDArray("DataArray", dtype, name, da.format())
+	if s != nil {
+		arr.values = data
+	}
+	da.setComponents(arr, n)
+
+	if da.ranges != nil && *da.ranges {
+		arr.RangeMin, arr.RangeMax = valueRange(data, arr.NumberOfComponents)
+	}
+	return da.insert(arr, bytes, s, opts...)
+}
+
+// addEncoded adds the data to the data array, see add, where the data is
+// already encoded in the format of the data array, e.g. as read from file.
+// The data is neither decoded nor encoded again.
+func (da *dataArray) addEncoded(name, dtype string, n int, encoded []byte,
+	opts ...ArrayOption) error {
+	if da.contains(name) {
+		msg := "%w: array already contains field '%s' in fields: %q"
+		return fmt.Errorf(msg, ErrDuplicateName, name, da.fieldNames())
+	}
+	if _, err := typeSize(dtype); err != nil {
 		return err
 	}
 
-	var format string
-	if da.appended != nil {
-		format = "appended"
-	} else {
-		format = da.encoder.format()
+	arr := newDArray("DataArray", dtype, name, da.format())
+	da.setComponents(arr, n)
+	return da.insert(arr, encoded, nil, opts...)
+}
+
+// insert applies the array options to the array and stores the array with
+// its encoded bytes or stream, see store.
+func (da *dataArray) insert(arr *darray, bytes []byte, s *stream,
+	opts ...ArrayOption) error {
+	for _, opt := range opts {
+		if err := opt(arr); err != nil {
+			return err
+		}
 	}
 
-	// get a new data array
-	arr := newDArray("DataArray", dtype, name, format)
+	da.store(arr, bytes, s)
+	da.Data = append(da.Data, arr)
+	return nil
+}
 
-	// set components
+// setComponents sets the number of components of the array, or the number
+// of tuples for field data.
+func (da *dataArray) setComponents(arr *darray, n int) {
 	if da.fieldData {
 		arr.NumberOfTuples = n
 	} else {
 		arr.NumberOfComponents = n
 	}
+}
+
+// format returns the format of the arrays, i.e. the encoder's format, or
+// "appended" for appended data.
+func (da *dataArray) format() string {
+	if da.appended != nil {
+		return formatAppended
+	}
+	if da.encoder == nil {
+		return ""
+	}
+	return da.encoder.format()
+}
+
+// encode converts the data into a payload, compresses it, and returns the
+// encoded bytes. When streaming, the data is only prepared to be encoded
+// while writing and the stream is returned instead.
+func (da *dataArray) encode(data interface{}) ([]byte, *stream, error) {
+	if da.streaming && da.appended != nil {
+		s, err := da.newStream(data)
+		return nil, s, err
+	}
 
-	// inline: save data and append
+	if da.encoder == nil {
+		return nil, nil, fmt.Errorf("%w: missing encoder, no format specified",
+			ErrEncoding)
+	}
+	payload, err := da.encoder.binarise(data, da.header64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// compress payload
+	payload, err = da.compressor.compress(payload)
+	if errors.Is(err, errHeaderOverflow) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCompression, err)
+	}
+
+	// encode payload as []byte
+	bytes, err := da.encoder.encode(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrEncoding, err)
+	}
+	return bytes, nil, nil
+}
+
+// store saves the encoded bytes of the array either inline or appended to
+// the external darray, in which case the array's offset is set. A stream is
+// appended to the external darray to be encoded while writing.
+func (da *dataArray) store(arr *darray, bytes []byte, s *stream) {
+	if s != nil {
+		da.appendStream(arr, s)
+		return
+	}
 	if da.appended == nil {
 		arr.Data = bytes
-		da.Data = append(da.Data, arr)
-		return nil
+		return
 	}
 
-	format = formatAppended
-
 	// appended data is required to start with underscore ("_")
 	if len(da.appended.Data) == 0 {
 		da.appended.Data = []byte("_")
@@ -182,13 +351,77 @@ func (da *dataArray) add(name string, n int, data interface{}) error {
 	// set offset: subtract 1 to correct for underscore
 	arr.Offset = new(int)
 	*arr.Offset = len(da.appended.Data) - 1
+	arr.size = len(bytes)
 
-	// store data, append array
+	// store data
 	da.appended.Data = append(da.appended.Data, bytes...)
-	da.Data = append(da.Data, arr)
+}
+
+// addIDs adds the point or cell ids, e.g. the connectivity or offsets, as
+// Int32. The ids are added as Int64 for IDType64() or when any of the ids
+// exceeds the range of Int32.
+func (da *dataArray) addIDs(name string, ids []int) error {
+	if da.idType64 {
+		return da.add(name, 1, int64s(ids))
+	}
+
+	res := make([]int32, len(ids))
+	for i, id := range ids {
+		if id > math.MaxInt32 || id < math.MinInt32 {
+			return da.add(name, 1, int64s(ids))
+		}
+		res[i] = int32(id)
+	}
+	return da.add(name, 1, res)
+}
+
+// reencode encodes and stores all arrays again, e.g. after changing the
+// header type. The arrays are decoded by d, which refers to the bytes as
+// encoded before.
+func (da *dataArray) reencode(d *xmlDecoder) error {
+	for _, arr := range da.Data {
+		values := arr.values
+		if values == nil {
+			var err error
+			if values, err = d.array(arr); err != nil {
+				return err
+			}
+		}
+
+		bytes, s, err := da.encode(values)
+		if err != nil {
+			return err
+		}
+		da.store(arr, bytes, s)
+	}
 	return nil
 }
 
+// decoder returns a decoder for the encoded bytes of the data array.
+func (da *dataArray) decoder() *xmlDecoder {
+	_, none := da.compressor.(noCompression)
+	d := &xmlDecoder{
+		header64:   da.header64,
+		compressed: da.compressor != nil && !none,
+		compressor: da.compressor,
+	}
+	if da.appended != nil && len(da.appended.Data) > 0 {
+		d.appended = da.appended.Data[1:]
+		d.encoding = da.appended.Encoding
+	}
+	return d
+}
+
+// decode returns the values of the array as a typed slice. Only streamed
+// arrays hold their values, all other arrays are decoded from their encoded
+// bytes.
+func (da *dataArray) decode(arr *darray) (interface{}, error) {
+	if arr.values != nil {
+		return arr.values, nil
+	}
+	return da.decoder().array(arr)
+}
+
 // Contains returns true if the identifier `name` is already used in any
 // darrays already present in the data array.
 func (da *dataArray) contains(name string) bool {
@@ -209,3 +442,61 @@ func (da *dataArray) fieldNames() []string {
 	}
 	return names
 }
+
+// lookup returns the darray with the identifier `name`, or nil when the data
+// array holds no such field.
+func (da *dataArray) lookup(name string) *darray {
+	for _, a := range da.Data {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// toInts converts a slice of any integer type to []int.
+func toInts(data interface{}) ([]int, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		msg := "%w: cannot convert %T to []int"
+		return nil, fmt.Errorf(msg, ErrUnsupportedType, data)
+	}
+
+	res := make([]int, v.Len())
+	for i := range res {
+		switch x := v.Index(i); x.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Int64:
+			res[i] = int(x.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Uint64:
+			res[i] = int(x.Uint())
+		default:
+			msg := "%w: cannot convert %T to []int"
+			return nil, fmt.Errorf(msg, ErrUnsupportedType, data)
+		}
+	}
+	return res, nil
+}
+
+// int64s converts a slice of int to []int64.
+func int64s(data []int) []int64 {
+	res := make([]int64, len(data))
+	for i, x := range data {
+		res[i] = int64(x)
+	}
+	return res
+}
+
+// cellTypes converts the cell types towards []uint8, as cell types are
+// written as UInt8.
+func cellTypes(types []int) ([]uint8, error) {
+	res := make([]uint8, len(types))
+	for i, t := range types {
+		if t < 0 || t > math.MaxUint8 {
+			return nil, fmt.Errorf("Cell %d has invalid cell type %d", i, t)
+		}
+		res[i] = uint8(t)
+	}
+	return res, nil
+}
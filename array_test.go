@@ -1,6 +1,7 @@
 package govtk
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -48,9 +49,18 @@ func TestDataType(t *testing.T) {
 	pairs := []pair{
 		pair{val: float32(1.0), str: "Float32"},
 		pair{val: float64(1.0), str: "Float64"},
-		pair{val: int(1), str: "UInt32"},
-		pair{val: int32(1), str: "UInt32"},
-		pair{val: int64(1), str: "UInt64"},
+		pair{val: int8(1), str: "Int8"},
+		pair{val: uint8(1), str: "UInt8"},
+		pair{val: int16(1), str: "Int16"},
+		pair{val: uint16(1), str: "UInt16"},
+		pair{val: int32(1), str: "Int32"},
+		pair{val: uint32(1), str: "UInt32"},
+		pair{val: int(1), str: "Int64"},
+		pair{val: int64(1), str: "Int64"},
+		pair{val: uint(1), str: "UInt64"},
+		pair{val: uint64(1), str: "UInt64"},
+		pair{val: []int{-1}, str: "Int64"},
+		pair{val: []uint16{1}, str: "UInt16"},
 	}
 	for _, pair := range pairs {
 		str, err := da.dataType(pair.val)
@@ -58,8 +68,9 @@ func TestDataType(t *testing.T) {
 			t.Error(err)
 		}
 
-		if !strings.EqualFold(str, pair.str) {
-			t.Error("Datatype strings are not equal")
+		if str != pair.str {
+			t.Errorf("Datatype strings are not equal: got %v, exp %v",
+				str, pair.str)
 		}
 	}
 
@@ -75,3 +86,47 @@ func TestDataType(t *testing.T) {
 
 	}
 }
+
+// arrays only hold their encoded bytes, which are decoded on demand
+func TestDecodeArray(t *testing.T) {
+	formats := map[string][]Option{
+		"ascii":      {Ascii()},
+		"binary":     {Binary()},
+		"compressed": {Binary(), Compressed()},
+		"appended":   {Appended()},
+		"raw":        {Raw()},
+		"raw_lz4":    {Raw(), CompressedWith(LZ4)},
+	}
+
+	for name, opts := range formats {
+		t.Run(name, func(t *testing.T) {
+			vti, err := Image(append(opts, WholeExtent(0, 1, 0, 1, 0, 1))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := map[string]interface{}{
+				"a": []float64{0, 1.5, 2, 3, 4, 5, 6, 7},
+				"b": []int32{1, 2, 3, 4, 5, 6, 7, 8},
+			}
+			err = vti.Add(PointData("a", exp["a"]), PointData("b", exp["b"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, arr := range vti.Grid.Pieces[0].PointData.Data {
+				if arr.values != nil {
+					t.Errorf("Array '%s' should not hold its values", arr.Name)
+				}
+			}
+			for name, data := range exp {
+				got, err := vti.PointArray(0, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, data) {
+					t.Errorf("Wrong values: got %v, exp %v", got, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package govtk
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ArrayOption sets additional properties of a single data array, passed
+// when adding the array by e.g. PointData or CellData.
+type ArrayOption func(a *darray) error
+
+// infoKey represents an information key of a data array. String values are
+// written as character data, while vectors hold their values by index.
+type infoKey struct {
+	Name     string      `xml:"name,attr"`
+	Location string      `xml:"location,attr"`
+	Length   int         `xml:"length,attr,omitempty"`
+	Value    string      `xml:",chardata"`
+	Values   []infoValue `xml:"Value"`
+}
+
+// infoValue holds a single value of a vector information key.
+type infoValue struct {
+	Index int    `xml:"index,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ComponentNames names the components of the array, e.g. "xx", "xy", etc.
+// for a tensor, instead of their index. The number of names should not
+// exceed the number of components.
+func ComponentNames(names ...string) ArrayOption {
+	return func(a *darray) error {
+		n := a.NumberOfComponents
+		if n == 0 {
+			n = 1
+		}
+		if len(names) > n {
+			msg := "Array '%s' has %d components, got %d component names"
+			return fmt.Errorf(msg, a.Name, n, len(names))
+		}
+
+		for i, name := range names {
+			a.Attrs = append(a.Attrs, xml.Attr{
+				Name:  xml.Name{Local: fmt.Sprintf("ComponentName%d", i)},
+				Value: name,
+			})
+		}
+		return nil
+	}
+}
+
+// InformationKey attaches the information key `name` with a string value to
+// the array. The location identifies the class defining the key, e.g.
+// "vtkDataArray" for the key "UNITS_LABEL".
+func InformationKey(location, name, value string) ArrayOption {
+	return func(a *darray) error {
+		a.Info = append(a.Info, infoKey{
+			Name:     name,
+			Location: location,
+			Value:    value,
+		})
+		return nil
+	}
+}
+
+// InformationVector attaches the information key `name` with a vector of
+// values to the array, e.g. the key "COMPONENT_RANGE" of location
+// "vtkDataArray", see InformationKey.
+func InformationVector(location, name string, values ...float64) ArrayOption {
+	return func(a *darray) error {
+		key := infoKey{
+			Name:     name,
+			Location: location,
+			Length:   len(values),
+		}
+		for i, v := range values {
+			key.Values = append(key.Values, infoValue{
+				Index: i,
+				Value: strconv.FormatFloat(v, 'g', -1, 64),
+			})
+		}
+		a.Info = append(a.Info, key)
+		return nil
+	}
+}
+
+// Units sets the units of the array, e.g. "m/s", shown by ParaView as part
+// of the array's name in e.g. the colour legend.
+func Units(label string) ArrayOption {
+	return InformationKey("vtkDataArray", "UNITS_LABEL", label)
+}
+
+// restoreArray returns the array options restoring the component names,
+// information keys, and range of a decoded array.
+func restoreArray(a *xmlArray) []ArrayOption {
+	var names []string
+	var lo, hi *float64
+	for _, attr := range a.Attrs {
+		switch attr.Name.Local {
+		case "RangeMin":
+			lo = parseRange(attr.Value)
+		case "RangeMax":
+			hi = parseRange(attr.Value)
+		}
+		if !strings.HasPrefix(attr.Name.Local, "ComponentName") {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimPrefix(attr.Name.Local,
+			"ComponentName"))
+		if err != nil || i < 0 {
+			continue
+		}
+		for len(names) <= i {
+			names = append(names, "")
+		}
+		names[i] = attr.Value
+	}
+
+	var opts []ArrayOption
+	if len(names) > 0 {
+		opts = append(opts, ComponentNames(names...))
+	}
+	if lo != nil && hi != nil {
+		opts = append(opts, func(a *darray) error {
+			a.RangeMin, a.RangeMax = lo, hi
+			return nil
+		})
+	}
+	if len(a.Info) > 0 {
+		info := a.Info
+		opts = append(opts, func(a *darray) error {
+			a.Info = append(a.Info, info...)
+			return nil
+		})
+	}
+	return opts
+}
+
+// parseRange parses the value of a RangeMin or RangeMax attribute, which is
+// nil when the value cannot be parsed.
+func parseRange(s string) *float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
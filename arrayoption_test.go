@@ -0,0 +1,71 @@
+package govtk
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArrayOptions(t *testing.T) {
+	names := []string{"xx", "xy", "xz", "yx", "yy", "yz", "zx", "zy", "zz"}
+	stress := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	for _, format := range []Option{Ascii(), Binary(), Raw()} {
+		vtu := newTetra(t, format)
+		err := vtu.Add(
+			CellData("stress", stress, ComponentNames(names...), Units("Pa"),
+				InformationVector("vtkDataArray", "COMPONENT_RANGE", 1, 9)),
+			FieldData("time", []float64{0.5}, Units("s")),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := vtu.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{
+			`ComponentName0="xx"`,
+			`ComponentName8="zz"`,
+			`<InformationKey name="UNITS_LABEL" location="vtkDataArray">Pa</InformationKey>`,
+			`<InformationKey name="COMPONENT_RANGE" location="vtkDataArray" length="2"><Value index="0">1</Value><Value index="1">9</Value></InformationKey>`,
+		} {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("Missing %s in output", s)
+			}
+		}
+
+		// data, component names, and information keys are restored
+		r, err := Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.CellArray(0, "stress")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, stress) {
+			t.Errorf("Wrong data: got %v, exp %v", got, stress)
+		}
+		arr := r.Grid.Pieces[0].CellData.lookup("stress")
+		exp := vtu.Grid.Pieces[0].CellData.lookup("stress")
+		if !reflect.DeepEqual(arr.Attrs, exp.Attrs) {
+			t.Errorf("Wrong attributes: got %v, exp %v", arr.Attrs, exp.Attrs)
+		}
+		if !reflect.DeepEqual(arr.Info, exp.Info) {
+			t.Errorf("Wrong information keys: got %v, exp %v", arr.Info, exp.Info)
+		}
+	}
+
+	// the number of names should not exceed the number of components
+	vtu := newTetra(t)
+	err := vtu.Add(PointData("p", []float64{1, 2, 3, 4}, ComponentNames("a", "b")))
+	if err == nil {
+		t.Errorf("Expected error for too many component names")
+	}
+	if vtu.Grid.Pieces[0].PointData.contains("p") {
+		t.Errorf("Array added despite error")
+	}
+}
@@ -0,0 +1,145 @@
+package govtk
+
+import "fmt"
+
+// ActiveScalars marks the point or cell data array `name` of the last piece
+// as the active scalars, e.g. used by ParaView for the default colouring. The
+// array should hold 1 to 4 components.
+func ActiveScalars(name string) Option {
+	return func(h *Header) error {
+		return h.setActive("Scalars", name)
+	}
+}
+
+// ActiveVectors marks the point or cell data array `name` of the last piece
+// as the active vectors, e.g. used to orient glyphs. The array should hold 3
+// components.
+func ActiveVectors(name string) Option {
+	return func(h *Header) error {
+		return h.setActive("Vectors", name)
+	}
+}
+
+// ActiveNormals marks the point or cell data array `name` of the last piece
+// as the active normals. The array should hold 3 components.
+func ActiveNormals(name string) Option {
+	return func(h *Header) error {
+		return h.setActive("Normals", name)
+	}
+}
+
+// ActiveTensors marks the point or cell data array `name` of the last piece
+// as the active tensors. The array should hold 9 components, or 6 for
+// symmetric tensors.
+func ActiveTensors(name string) Option {
+	return func(h *Header) error {
+		return h.setActive("Tensors", name)
+	}
+}
+
+// ActiveTCoords marks the point or cell data array `name` of the last piece
+// as the active texture coordinates. The array should hold 1 to 3
+// components.
+func ActiveTCoords(name string) Option {
+	return func(h *Header) error {
+		return h.setActive("TCoords", name)
+	}
+}
+
+// setActive sets the attribute `kind` of the point and cell data holding the
+// array `name`, after verifying its number of components.
+func (h *Header) setActive(kind, name string) error {
+	lp, err := h.lastPiece()
+	if err != nil {
+		return err
+	}
+
+	var groups []*dataArray
+	for _, da := range []*dataArray{lp.PointData, lp.CellData} {
+		if da == nil {
+			continue
+		}
+		if arr := da.lookup(name); arr != nil {
+			if err := checkComponents(kind, arr.NumberOfComponents); err != nil {
+				return fmt.Errorf("%v for array '%s'", err, name)
+			}
+			groups = append(groups, da)
+		}
+	}
+	if len(groups) == 0 {
+		msg := "No point or cell data array '%s' to set as %s"
+		return fmt.Errorf(msg, name, kind)
+	}
+
+	for _, da := range groups {
+		da.attributes.set(kind, name)
+	}
+	return nil
+}
+
+// checkComponents returns an error when the number of components does not
+// match the attribute kind.
+func checkComponents(kind string, n int) error {
+	var valid bool
+	switch kind {
+	case "Scalars":
+		valid = n >= 1 && n <= 4
+	case "Vectors", "Normals":
+		valid = n == 3
+	case "Tensors":
+		valid = n == 6 || n == 9
+	case "TCoords":
+		valid = n >= 1 && n <= 3
+	}
+	if !valid {
+		return fmt.Errorf("Invalid number of components %d for %s", n, kind)
+	}
+	return nil
+}
+
+// set sets the attribute `kind` to the array `name`.
+func (a *attributes) set(kind, name string) {
+	switch kind {
+	case "Scalars":
+		a.Scalars = name
+	case "Vectors":
+		a.Vectors = name
+	case "Normals":
+		a.Normals = name
+	case "Tensors":
+		a.Tensors = name
+	case "TCoords":
+		a.TCoords = name
+	}
+}
+
+// checkAttributes checks the active attributes of the data array refer to
+// arrays of the data array with a valid number of components.
+func checkAttributes(da *dataArray, group string) error {
+	if da == nil {
+		return nil
+	}
+
+	a := da.attributes
+	active := []struct{ kind, name string }{
+		{"Scalars", a.Scalars},
+		{"Vectors", a.Vectors},
+		{"Normals", a.Normals},
+		{"Tensors", a.Tensors},
+		{"TCoords", a.TCoords},
+	}
+	for _, act := range active {
+		if act.name == "" {
+			continue
+		}
+		arr := da.lookup(act.name)
+		if arr == nil {
+			msg := "%s: %s refers to missing array '%s'"
+			return fmt.Errorf(msg, group, act.kind, act.name)
+		}
+		if err := checkComponents(act.kind, arr.NumberOfComponents); err != nil {
+			return fmt.Errorf("%s: %v for array '%s'", group, err, act.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package govtk
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestActiveAttributes(t *testing.T) {
+	vtu := newTetra(t)
+	err := vtu.Add(
+		PointData("velocity", make([]float64, 12)),
+		PointData("normal", make([]float64, 12)),
+		CellData("stress", make([]float64, 9)),
+		CellData("temperature", []float64{1}),
+		ActiveScalars("temperature"),
+		ActiveVectors("velocity"),
+		ActiveNormals("normal"),
+		ActiveTensors("stress"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vtu.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := vtu.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// arrays present in both point and cell data are marked in both
+	for _, s := range []string{
+		`<PointData Scalars="temperature" Vectors="velocity" Normals="normal">`,
+		`<CellData Scalars="temperature" Vectors="velocity" Tensors="stress">`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Missing %s in output", s)
+		}
+	}
+
+	// the attributes are restored when reading
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := r.Grid.Pieces[0].PointData.attributes; a.Vectors != "velocity" {
+		t.Errorf("Vectors not restored: %+v", a)
+	}
+
+	// the arrays should exist and match the number of components
+	for _, opt := range []Option{
+		ActiveScalars("pressure"),
+		ActiveVectors("temperature"),
+		ActiveNormals("stress"),
+		ActiveTensors("velocity"),
+		ActiveTCoords("stress"),
+	} {
+		if err := vtu.Add(opt); err == nil {
+			t.Errorf("Expected error for invalid active attribute")
+		}
+	}
+
+	vtu.Grid.Pieces[0].CellData.Scalars = "pressure"
+	if err := vtu.Validate(); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("Expected invalid mesh, got: %v", err)
+	}
+}
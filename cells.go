@@ -0,0 +1,103 @@
+package govtk
+
+// CellInfo describes a VTK cell type.
+type CellInfo struct {
+	// Type is the VTK cell type, e.g. Tetra.
+	Type int
+
+	// Name is the name of the cell type as used by VTK, e.g. "VTK_TETRA".
+	Name string
+
+	// Dimension is the topological dimension of the cell: 0 for vertices,
+	// 1 for lines, 2 for surfaces, and 3 for volumes.
+	Dimension int
+
+	// Nodes is the number of nodes of the cell, or -1 for cells with a
+	// variable number of nodes, e.g. Polygon or the Lagrange cells.
+	Nodes int
+}
+
+// cellCatalogue holds all VTK cell types ordered by type, i.e. the types of
+// VTK's vtkCellType.h.
+var cellCatalogue = []CellInfo{
+	{EmptyCell, "VTK_EMPTY_CELL", 0, 0},
+	{Vertex, "VTK_VERTEX", 0, 1},
+	{PolyVertex, "VTK_POLY_VERTEX", 0, -1},
+	{Line, "VTK_LINE", 1, 2},
+	{PolyLine, "VTK_POLY_LINE", 1, -1},
+	{Triangle, "VTK_TRIANGLE", 2, 3},
+	{TriangleStrip, "VTK_TRIANGLE_STRIP", 2, -1},
+	{Polygon, "VTK_POLYGON", 2, -1},
+	{Pixel, "VTK_PIXEL", 2, 4},
+	{Quad, "VTK_QUAD", 2, 4},
+	{Tetra, "VTK_TETRA", 3, 4},
+	{Voxel, "VTK_VOXEL", 3, 8},
+	{Hexahedron, "VTK_HEXAHEDRON", 3, 8},
+	{Wedge, "VTK_WEDGE", 3, 6},
+	{Pyramid, "VTK_PYRAMID", 3, 5},
+	{PentagonalPrism, "VTK_PENTAGONAL_PRISM", 3, 10},
+	{HexagonalPrism, "VTK_HEXAGONAL_PRISM", 3, 12},
+	{QuadraticEdge, "VTK_QUADRATIC_EDGE", 1, 3},
+	{QuadraticTriangle, "VTK_QUADRATIC_TRIANGLE", 2, 6},
+	{QuadraticQuad, "VTK_QUADRATIC_QUAD", 2, 8},
+	{QuadraticTetra, "VTK_QUADRATIC_TETRA", 3, 10},
+	{QuadraticHexahedron, "VTK_QUADRATIC_HEXAHEDRON", 3, 20},
+	{QuadraticWedge, "VTK_QUADRATIC_WEDGE", 3, 15},
+	{QuadraticPyramid, "VTK_QUADRATIC_PYRAMID", 3, 13},
+	{BiQuadraticQuad, "VTK_BIQUADRATIC_QUAD", 2, 9},
+	{TriQuadraticHexahedron, "VTK_TRIQUADRATIC_HEXAHEDRON", 3, 27},
+	{QuadraticLinearQuad, "VTK_QUADRATIC_LINEAR_QUAD", 2, 6},
+	{QuadraticLinearWedge, "VTK_QUADRATIC_LINEAR_WEDGE", 3, 12},
+	{BiQuadraticQuadraticWedge, "VTK_BIQUADRATIC_QUADRATIC_WEDGE", 3, 18},
+	{BiQuadraticQuadraticHexahedron, "VTK_BIQUADRATIC_QUADRATIC_HEXAHEDRON", 3, 24},
+	{BiQuadraticTriangle, "VTK_BIQUADRATIC_TRIANGLE", 2, 7},
+	{CubicLine, "VTK_CUBIC_LINE", 1, 4},
+	{QuadraticPolygon, "VTK_QUADRATIC_POLYGON", 2, -1},
+	{TriQuadraticPyramid, "VTK_TRIQUADRATIC_PYRAMID", 3, 19},
+	{ConvexPointSet, "VTK_CONVEX_POINT_SET", 3, -1},
+	{Polyhedron, "VTK_POLYHEDRON", 3, -1},
+	{ParametricCurve, "VTK_PARAMETRIC_CURVE", 1, -1},
+	{ParametricSurface, "VTK_PARAMETRIC_SURFACE", 2, -1},
+	{ParametricTriSurface, "VTK_PARAMETRIC_TRI_SURFACE", 2, -1},
+	{ParametricQuadSurface, "VTK_PARAMETRIC_QUAD_SURFACE", 2, -1},
+	{ParametricTetraRegion, "VTK_PARAMETRIC_TETRA_REGION", 3, -1},
+	{ParametricHexRegion, "VTK_PARAMETRIC_HEX_REGION", 3, -1},
+	{HigherOrderEdge, "VTK_HIGHER_ORDER_EDGE", 1, -1},
+	{HigherOrderTriangle, "VTK_HIGHER_ORDER_TRIANGLE", 2, -1},
+	{HigherOrderQuad, "VTK_HIGHER_ORDER_QUAD", 2, -1},
+	{HigherOrderPolygon, "VTK_HIGHER_ORDER_POLYGON", 2, -1},
+	{HigherOrderTetrahedron, "VTK_HIGHER_ORDER_TETRAHEDRON", 3, -1},
+	{HigherOrderWedge, "VTK_HIGHER_ORDER_WEDGE", 3, -1},
+	{HigherOrderPyramid, "VTK_HIGHER_ORDER_PYRAMID", 3, -1},
+	{HigherOrderHexahedron, "VTK_HIGHER_ORDER_HEXAHEDRON", 3, -1},
+	{LagrangeCurve, "VTK_LAGRANGE_CURVE", 1, -1},
+	{LagrangeTriangle, "VTK_LAGRANGE_TRIANGLE", 2, -1},
+	{LagrangeQuadrilateral, "VTK_LAGRANGE_QUADRILATERAL", 2, -1},
+	{LagrangeTetrahedron, "VTK_LAGRANGE_TETRAHEDRON", 3, -1},
+	{LagrangeHexahedron, "VTK_LAGRANGE_HEXAHEDRON", 3, -1},
+	{LagrangeWedge, "VTK_LAGRANGE_WEDGE", 3, -1},
+	{LagrangePyramid, "VTK_LAGRANGE_PYRAMID", 3, -1},
+	{BezierCurve, "VTK_BEZIER_CURVE", 1, -1},
+	{BezierTriangle, "VTK_BEZIER_TRIANGLE", 2, -1},
+	{BezierQuadrilateral, "VTK_BEZIER_QUADRILATERAL", 2, -1},
+	{BezierTetrahedron, "VTK_BEZIER_TETRAHEDRON", 3, -1},
+	{BezierHexahedron, "VTK_BEZIER_HEXAHEDRON", 3, -1},
+	{BezierWedge, "VTK_BEZIER_WEDGE", 3, -1},
+	{BezierPyramid, "VTK_BEZIER_PYRAMID", 3, -1},
+}
+
+// CellTypes returns the description of all VTK cell types ordered by type.
+func CellTypes() []CellInfo {
+	return append([]CellInfo(nil), cellCatalogue...)
+}
+
+// LookupCell returns the description of the VTK cell type. The boolean is
+// false when the cell type is unknown.
+func LookupCell(cellType int) (CellInfo, bool) {
+	for _, c := range cellCatalogue {
+		if c.Type == cellType {
+			return c, true
+		}
+	}
+	return CellInfo{}, false
+}
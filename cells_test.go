@@ -0,0 +1,61 @@
+package govtk
+
+import "testing"
+
+func TestCellTypes(t *testing.T) {
+	cells := CellTypes()
+	for i := 1; i < len(cells); i++ {
+		if cells[i].Type <= cells[i-1].Type {
+			t.Errorf("Cell types not ordered: %v after %v", cells[i].Name,
+				cells[i-1].Name)
+		}
+	}
+
+	// the returned slice is a copy
+	cells[0].Nodes = 100
+	if c, _ := LookupCell(EmptyCell); c.Nodes != 0 {
+		t.Errorf("Catalogue modified through CellTypes")
+	}
+
+	tests := []struct {
+		cellType  int
+		name      string
+		dim       int
+		nodes     int
+		valueType int
+	}{
+		{Tetra, "VTK_TETRA", 3, 4, 10},
+		{HexagonalPrism, "VTK_HEXAGONAL_PRISM", 3, 12, 16},
+		{QuadraticHexahedron, "VTK_QUADRATIC_HEXAHEDRON", 3, 20, 25},
+		{QuadraticWedge, "VTK_QUADRATIC_WEDGE", 3, 15, 26},
+		{TriQuadraticHexahedron, "VTK_TRIQUADRATIC_HEXAHEDRON", 3, 27, 29},
+		{BiQuadraticTriangle, "VTK_BIQUADRATIC_TRIANGLE", 2, 7, 34},
+		{CubicLine, "VTK_CUBIC_LINE", 1, 4, 35},
+		{TriQuadraticPyramid, "VTK_TRIQUADRATIC_PYRAMID", 3, 19, 37},
+		{Polyhedron, "VTK_POLYHEDRON", 3, -1, 42},
+		{ParametricCurve, "VTK_PARAMETRIC_CURVE", 1, -1, 51},
+		{ParametricHexRegion, "VTK_PARAMETRIC_HEX_REGION", 3, -1, 56},
+		{HigherOrderEdge, "VTK_HIGHER_ORDER_EDGE", 1, -1, 60},
+		{HigherOrderHexahedron, "VTK_HIGHER_ORDER_HEXAHEDRON", 3, -1, 67},
+		{LagrangeCurve, "VTK_LAGRANGE_CURVE", 1, -1, 68},
+		{BezierPyramid, "VTK_BEZIER_PYRAMID", 3, -1, 81},
+	}
+	for _, test := range tests {
+		if test.cellType != test.valueType {
+			t.Errorf("Wrong value of %s: got %d, exp %d", test.name,
+				test.cellType, test.valueType)
+		}
+		c, ok := LookupCell(test.cellType)
+		if !ok {
+			t.Errorf("Missing cell type %s", test.name)
+			continue
+		}
+		if c.Name != test.name || c.Dimension != test.dim || c.Nodes != test.nodes {
+			t.Errorf("Wrong cell info: got %+v", c)
+		}
+	}
+
+	if _, ok := LookupCell(17); ok {
+		t.Errorf("Unknown cell type should not be found")
+	}
+}
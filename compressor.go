@@ -1,9 +1,14 @@
 package govtk
 
 import (
+	"bytes"
 	"compress/zlib"
-	"encoding/binary"
+	"fmt"
 	"io"
+	"sync"
+
+	"github.com/pierrec/lz4"
+	"github.com/ulikunitz/xz"
 )
 
 // These constants are copied from the zlib package, which are in turn copied
@@ -24,6 +29,38 @@ type compressor interface {
 	decompress(p *payload) (*payload, error)
 }
 
+// The blockCompressor interface is satisfied by compressors that compress
+// the data in individual blocks, see compressBlocks.
+type blockCompressor interface {
+	compressor
+
+	// compressBlock writes a single compressed block to dst.
+	compressBlock(dst io.Writer, src []byte) error
+
+	// bytesPerBlock returns the number of uncompressed bytes per block.
+	bytesPerBlock() int
+
+	// numWorkers returns the number of blocks compressed concurrently.
+	numWorkers() int
+}
+
+// bytesPerBlock returns the block size, or DefaultBlockSize when the block
+// size is non-positive.
+func bytesPerBlock(blockSize int) int {
+	if blockSize <= 0 {
+		return DefaultBlockSize
+	}
+	return blockSize
+}
+
+// numWorkers returns the number of workers, which is at least one.
+func numWorkers(workers int) int {
+	if workers < 1 {
+		return 1
+	}
+	return workers
+}
+
 // Satifies the compressor iterface, without applying any (de)compression.
 type noCompression struct{}
 
@@ -31,7 +68,9 @@ type noCompression struct{}
 func (nc noCompression) compress(p *payload) (*payload, error) {
 	if p.head.Len() == 0 {
 		// insert header if not set
-		p.setHeader()
+		if err := p.setHeader(); err != nil {
+			return nil, err
+		}
 	}
 	return p, nil
 }
@@ -45,68 +84,303 @@ func (nc noCompression) String() string {
 	return `Compressor: no compression`
 }
 
+// DefaultBlockSize is the number of uncompressed bytes per compressed block
+// as used by VTK's own writers.
+const DefaultBlockSize = 1 << 15
+
 // Satisfies the compressor interface using compress/zlib for (de)compression.
+// The data is split into blocks of blockSize bytes, which are compressed
+// individually. A non-positive blockSize applies DefaultBlockSize. The blocks
+// are compressed concurrently by the given number of workers.
 type zlibCompression struct {
-	level int
+	level     int
+	blockSize int
+	workers   int
 }
 
 // Compress returns a compressed copy of the provided payload and updates
 // the payload's header.
 func (z zlibCompression) compress(p *payload) (*payload, error) {
-	c := newPayload()
+	return compressBlocks(p, z.bytesPerBlock(), z.numWorkers(), z.compressBlock)
+}
 
-	// zlib writer
-	writer, err := zlib.NewWriterLevel(c.body, z.level)
+// CompressBlock writes a single zlib compressed block to dst.
+func (z zlibCompression) compressBlock(dst io.Writer, src []byte) error {
+	writer, err := zlib.NewWriterLevel(dst, z.level)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	if _, err := writer.Write(src); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func (z zlibCompression) bytesPerBlock() int { return bytesPerBlock(z.blockSize) }
+func (z zlibCompression) numWorkers() int    { return numWorkers(z.workers) }
 
-	// compress data and capture uncompressed payload's size
-	n, err := io.Copy(writer, p.body)
+// Decompress returns a decompressed copy of the provided payload and updates
+// the payload's header.
+func (z zlibCompression) decompress(p *payload) (*payload, error) {
+	return decompressBlocks(p, func(dst io.Writer, src []byte, size int) error {
+		reader, err := zlib.NewReader(bytes.NewReader(src))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, reader); err != nil {
+			return err
+		}
+		return reader.Close()
+	})
+}
+
+func (z zlibCompression) String() string {
+	return `Compressor: compress/zlib`
+}
+
+// compressBlocks splits the body of the payload into blocks of blockSize
+// bytes and compresses each block individually using the provided function,
+// running the given number of workers concurrently.
+// The compressed payload's header follows VTK's layout:
+//
+//	number of blocks
+//	bytes of an uncompressed block
+//	bytes of the last uncompressed block (0 if it is a full block)
+//	bytes of each compressed block
+func compressBlocks(p *payload, blockSize, workers int,
+	compress func(dst io.Writer, src []byte) error) (*payload, error) {
+	c := newPayload()
+	c.header64 = p.header64
+
+	data := p.body.Bytes()
+	n := len(data)
+	header := []uint64{0, uint64(blockSize), uint64(n % blockSize)}
+
+	var blocks [][]byte
+	for start := 0; start < n; start += blockSize {
+		end := start + blockSize
+		if end > n {
+			end = n
+		}
+		blocks = append(blocks, data[start:end])
+	}
+
+	compressed, err := compressAll(blocks, workers, compress)
 	if err != nil {
 		return nil, err
 	}
+	for _, b := range compressed {
+		header[0]++
+		header = append(header, uint64(b.Len()))
+		c.body.Write(b.Bytes())
+	}
 
-	if err := writer.Close(); err != nil {
+	if err := c.setHeaderValues(header); err != nil {
 		return nil, err
 	}
+	return c, nil
+}
 
-	// write the header
-	header := []int32{1, int32(n), int32(n), int32(c.body.Len())}
-	for _, val := range header {
-		err := binary.Write(c.head, binary.LittleEndian, val)
+// compressAll compresses the blocks individually and returns the compressed
+// blocks in order. The blocks are divided over the given number of workers,
+// which compress concurrently.
+func compressAll(blocks [][]byte, workers int,
+	compress func(dst io.Writer, src []byte) error) ([]*bytes.Buffer, error) {
+	out := make([]*bytes.Buffer, len(blocks))
+	errs := make([]error, len(blocks))
+	for i := range out {
+		out[i] = new(bytes.Buffer)
+	}
+
+	if workers > len(blocks) {
+		workers = len(blocks)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = compress(out[i], blocks[i])
+			}
+		}()
+	}
+	for i := range blocks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	return c, nil
+	return out, nil
 }
 
-// Decompress returns a decompressed copy of the provided payload and updates
-// the payload's header.
-func (z zlibCompression) decompress(p *payload) (*payload, error) {
+// decompressBlocks decompresses the body of the payload block by block using
+// the block sizes given in the header. The provided function receives the
+// compressed block and its uncompressed size. A payload without header is
+// decompressed as a single block of unknown (-1) uncompressed size.
+func decompressBlocks(p *payload,
+	decompress func(dst io.Writer, src []byte, size int) error) (*payload, error) {
 	d := newPayload()
+	d.header64 = p.header64
 
-	reader, err := zlib.NewReader(p.body)
-	if err != nil {
+	sizes := []uint64{uint64(p.body.Len())}
+	usizes := []int{-1}
+	if p.head.Len() > 0 {
+		var err error
+		if sizes, err = p.blockSizes(); err != nil {
+			return nil, err
+		}
+		vals, err := p.uncompressedSizes()
+		if err != nil {
+			return nil, err
+		}
+		usizes = make([]int, len(vals))
+		for i, v := range vals {
+			usizes[i] = int(v)
+		}
+	}
+
+	data := p.body.Bytes()
+	for i, size := range sizes {
+		if uint64(len(data)) < size {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err := decompress(d.body, data[:size], usizes[i]); err != nil {
+			return nil, err
+		}
+		data = data[size:]
+	}
+
+	if err := d.setHeader(); err != nil {
 		return nil, err
 	}
+	return d, nil
+}
+
+// Satisfies the compressor interface using LZ4 block compression, as done by
+// VTK's vtkLZ4DataCompressor. Levels of BestCompression and up apply the
+// slower high compression variant. The data is compressed in blocks, similar
+// to zlibCompression.
+type lz4Compression struct {
+	level     int
+	blockSize int
+	workers   int
+}
+
+// Compress returns a compressed copy of the provided payload and updates
+// the payload's header.
+func (l lz4Compression) compress(p *payload) (*payload, error) {
+	return compressBlocks(p, l.bytesPerBlock(), l.numWorkers(), l.compressBlock)
+}
 
-	// decompress data
-	_, err = io.Copy(d.body, reader)
+// lz4Tables holds the hash tables used for LZ4 block compression.
+var lz4Tables = sync.Pool{
+	New: func() interface{} { return new([1 << 16]int) },
+}
+
+// CompressBlock writes a single LZ4 compressed block to dst. The hash table
+// is cleared before compressing, as stale entries result in different, yet
+// valid, output for the same data. Deterministic output is required to
+// compute the compressed sizes up front, see Streaming().
+func (l lz4Compression) compressBlock(dst io.Writer, src []byte) error {
+	buf := make([]byte, lz4.CompressBlockBound(len(src)))
+
+	var n int
+	var err error
+	if l.level >= BestCompression {
+		n, err = lz4.CompressBlockHC(src, buf, 0)
+	} else {
+		table := lz4Tables.Get().(*[1 << 16]int)
+		*table = [1 << 16]int{}
+		n, err = lz4.CompressBlock(src, buf, table[:])
+		lz4Tables.Put(table)
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
+	_, err = dst.Write(buf[:n])
+	return err
+}
 
-	if err := reader.Close(); err != nil {
-		return nil, err
+func (l lz4Compression) bytesPerBlock() int { return bytesPerBlock(l.blockSize) }
+func (l lz4Compression) numWorkers() int    { return numWorkers(l.workers) }
+
+// Decompress returns a decompressed copy of the provided payload and updates
+// the payload's header. LZ4 blocks require their uncompressed size, hence
+// the payload requires a header.
+func (l lz4Compression) decompress(p *payload) (*payload, error) {
+	return decompressBlocks(p, func(dst io.Writer, src []byte, size int) error {
+		if size < 0 {
+			return fmt.Errorf("Unknown uncompressed size of LZ4 block")
+		}
+		buf := make([]byte, size)
+		n, err := lz4.UncompressBlock(src, buf)
+		if err != nil {
+			return err
+		}
+		if n != size {
+			return fmt.Errorf("LZ4 block of %d bytes, expected %d", n, size)
+		}
+		_, err = dst.Write(buf)
+		return err
+	})
+}
+
+func (l lz4Compression) String() string {
+	return `Compressor: lz4`
+}
+
+// Satisfies the compressor interface using LZMA compression, as done by
+// VTK's vtkLZMADataCompressor. Each block is stored in the xz container
+// format with a CRC64 checksum. Unlike VTK, which passes the level as xz
+// preset, the compression level is ignored.
+type lzmaCompression struct {
+	blockSize int
+	workers   int
+}
+
+// Compress returns a compressed copy of the provided payload and updates
+// the payload's header.
+func (l lzmaCompression) compress(p *payload) (*payload, error) {
+	return compressBlocks(p, l.bytesPerBlock(), l.numWorkers(), l.compressBlock)
+}
+
+// CompressBlock writes a single LZMA compressed block, i.e. an xz stream, to
+// dst.
+func (l lzmaCompression) compressBlock(dst io.Writer, src []byte) error {
+	writer, err := xz.WriterConfig{CheckSum: xz.CRC64}.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(src); err != nil {
+		return err
 	}
+	return writer.Close()
+}
 
-	d.setHeader()
-	return d, nil
+func (l lzmaCompression) bytesPerBlock() int { return bytesPerBlock(l.blockSize) }
+func (l lzmaCompression) numWorkers() int    { return numWorkers(l.workers) }
+
+// Decompress returns a decompressed copy of the provided payload and updates
+// the payload's header.
+func (l lzmaCompression) decompress(p *payload) (*payload, error) {
+	return decompressBlocks(p, func(dst io.Writer, src []byte, size int) error {
+		reader, err := xz.NewReader(bytes.NewReader(src))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(dst, reader)
+		return err
+	})
 }
 
-func (z zlibCompression) String() string {
-	return `Compressor: compress/zlib`
+func (l lzmaCompression) String() string {
+	return `Compressor: lzma`
 }
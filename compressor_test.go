@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"reflect"
 	"testing"
 )
 
@@ -37,32 +38,67 @@ func TestNoCompressedHeader(t *testing.T) {
 // todo add test to verify if paraview is emtpy or not without a header
 // results in problems
 
-// verify compressors modify header to 4*int32
+// verify compressors write the multi-block header: number of blocks, block
+// size, size of the last partial block, and the compressed size per block
 func TestCompressedHeader(t *testing.T) {
-
-	compressors := []compressor{zlibCompression{}}
-
-	for _, compressor := range compressors {
-		t.Run(fmt.Sprintf("%v", compressor), func(t *testing.T) {
-
-			p := newPayload()
-			c, err := compressor.compress(p)
-			if err != nil {
-				t.Errorf("Compress error %v", err)
-			}
-
-			// after compression header should contain 4x int32 as bytes
-			if c.head.Len() != 4*4 {
-				t.Errorf("Wrong length, exp: %v, got %v", 16, c.head.Len())
-			}
-
-			// payload recognise compression
-			if !c.isCompressed() {
-				t.Errorf("Does not detect compressed header.")
-			}
-		})
+	tests := []struct {
+		n, blockSize int
+		exp          []uint64
+	}{
+		{0, 8, []uint64{0, 8, 0}},
+		{5, 8, []uint64{1, 8, 5}},
+		{16, 8, []uint64{2, 8, 0}},
+		{20, 8, []uint64{3, 8, 4}},
+		{20, 0, []uint64{1, DefaultBlockSize, 20}},
 	}
 
+	for _, test := range tests {
+		compressors := []compressor{
+			zlibCompression{blockSize: test.blockSize},
+			lz4Compression{blockSize: test.blockSize},
+			lzmaCompression{blockSize: test.blockSize},
+		}
+		for _, compressor := range compressors {
+			name := fmt.Sprintf("%v/%d/%d", compressor, test.n, test.blockSize)
+			t.Run(name, func(t *testing.T) {
+				p := newPayload()
+				p.body.Write(bytes.Repeat([]byte{7}, test.n))
+
+				c, err := compressor.compress(p)
+				if err != nil {
+					t.Fatalf("Compress error %v", err)
+				}
+
+				vals, err := c.headerValues()
+				if err != nil {
+					t.Fatal(err)
+				}
+				nblocks := int(test.exp[0])
+				if len(vals) != 3+nblocks {
+					t.Fatalf("Wrong header length: got %v, exp %v",
+						len(vals), 3+nblocks)
+				}
+				if !reflect.DeepEqual(vals[:3], test.exp) {
+					t.Errorf("Wrong header: got %v, exp %v", vals[:3], test.exp)
+				}
+
+				// compressed sizes add up to the body
+				var sum uint64
+				for _, size := range vals[3:] {
+					sum += size
+				}
+				if sum != uint64(c.body.Len()) {
+					t.Errorf("Compressed sizes %v do not match body %d",
+						vals[3:], c.body.Len())
+				}
+
+				// payload recognise compression
+				if !c.isCompressed() {
+					t.Errorf("Does not detect compressed header.")
+				}
+			})
+		}
+	}
 }
 
 // TestCompressors asserts content of payload remains equal for the
@@ -74,7 +110,13 @@ func TestCompressors(t *testing.T) {
 		[]int{1, 2},
 		[]int{1, 2, 3}}
 
-	compressors := []compressor{noCompression{}, zlibCompression{}}
+	// multiple blocks, including a partial last block
+	ints = append(ints, make([]int, 100))
+
+	compressors := []compressor{noCompression{}, zlibCompression{},
+		zlibCompression{blockSize: 24}, lz4Compression{},
+		lz4Compression{level: BestCompression, blockSize: 24},
+		lzmaCompression{}, lzmaCompression{blockSize: 24}}
 
 	for _, compressor := range compressors {
 		t.Run(fmt.Sprintf("%v", compressor), func(t *testing.T) {
@@ -83,6 +125,43 @@ func TestCompressors(t *testing.T) {
 	}
 }
 
+// Ensure concurrent workers result in the same output as a single worker.
+func TestCompressionWorkers(t *testing.T) {
+	data := make([]float64, 10000)
+	for i := range data {
+		data[i] = float64(i % 97)
+	}
+
+	tests := []struct {
+		single, multi compressor
+	}{
+		{zlibCompression{blockSize: 1000},
+			zlibCompression{blockSize: 1000, workers: 8}},
+		{lz4Compression{blockSize: 1000},
+			lz4Compression{blockSize: 1000, workers: 8}},
+		{lzmaCompression{blockSize: 1000},
+			lzmaCompression{blockSize: 1000, workers: 3}},
+	}
+	for _, test := range tests {
+		p, err := newPayloadFromData(data, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, err := test.single.compress(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := test.multi.compress(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(exp.head.Bytes(), got.head.Bytes()) ||
+			!bytes.Equal(exp.body.Bytes(), got.body.Bytes()) {
+			t.Errorf("%v: concurrent compression differs", test.multi)
+		}
+	}
+}
+
 // TestCompressDecompress verifies payload after compressing, decompressing.
 func testCompressDecompress(cases [][]int, c compressor, t *testing.T) {
 
@@ -3,71 +3,114 @@ package govtk
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/binary"
 	"fmt"
-	"io"
-	"log"
+	"reflect"
+	"strconv"
 )
 
 // The encoder interface provides functionality to convert int or float data
 // towards a payload. Additionally, the encoder encodes the payload's data.
 type encoder interface {
-	binarise(data interface{}) *payload
+	binarise(data interface{}, header64 bool) (*payload, error)
 	encode(*payload) ([]byte, error)
-	//decode([]byte) *Payload // todo
 	format() string
 }
 
-// asciier encodes the payload using the ascii format.
-type asciier struct{}
+// asciier encodes the payload using the ascii format. Floating point values
+// are written using the number of significant digits given by precision,
+// where a negative precision gives the shortest representation that reads
+// back to the exact same value.
+type asciier struct {
+	precision int
+}
 
 // Binarise creates a payload where the body is filled with the bytes of the
 // string representation of the provided data. A space (" ") is inserted
 // after each element of the data, except after the last.
-func (a asciier) binarise(data interface{}) *payload {
+func (a asciier) binarise(data interface{}, header64 bool) (*payload, error) {
 	p := newPayload()
-
-	// temp func to write []byte to buffer
-	writeVal := func(buf io.Writer, data []byte) {
-		err := binary.Write(buf, binary.LittleEndian, data)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-
-	// temp func to write a separator (" ") to buffer
-	writeSep := func(buf io.Writer) {
-		err := binary.Write(buf, binary.LittleEndian, []byte(" "))
-		if err != nil {
-			log.Fatal(err)
-		}
+	p.header64 = header64
+
+	// single values are written as a slice of length one
+	values := data
+	if v := reflect.ValueOf(data); v.IsValid() && v.Kind() != reflect.Slice {
+		s := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+		s.Index(0).Set(v)
+		values = s.Interface()
 	}
 
 	// each type needs a string conversion before writing to buffer
-	switch v := data.(type) {
+	var buf []byte
+	switch v := values.(type) {
+	case []int8:
+		for i, x := range v {
+			buf = strconv.AppendInt(separate(buf, i), int64(x), 10)
+		}
+	case []uint8:
+		for i, x := range v {
+			buf = strconv.AppendUint(separate(buf, i), uint64(x), 10)
+		}
+	case []int16:
+		for i, x := range v {
+			buf = strconv.AppendInt(separate(buf, i), int64(x), 10)
+		}
+	case []uint16:
+		for i, x := range v {
+			buf = strconv.AppendUint(separate(buf, i), uint64(x), 10)
+		}
+	case []int32:
+		for i, x := range v {
+			buf = strconv.AppendInt(separate(buf, i), int64(x), 10)
+		}
+	case []uint32:
+		for i, x := range v {
+			buf = strconv.AppendUint(separate(buf, i), uint64(x), 10)
+		}
 	case []int:
 		for i, x := range v {
-			writeVal(p.body, []byte(fmt.Sprintf("%d", x)))
-			if i < len(v)-1 {
-				writeSep(p.body)
-			}
+			buf = strconv.AppendInt(separate(buf, i), int64(x), 10)
+		}
+	case []uint:
+		for i, x := range v {
+			buf = strconv.AppendUint(separate(buf, i), uint64(x), 10)
+		}
+	case []int64:
+		for i, x := range v {
+			buf = strconv.AppendInt(separate(buf, i), x, 10)
+		}
+	case []uint64:
+		for i, x := range v {
+			buf = strconv.AppendUint(separate(buf, i), x, 10)
+		}
+	case []float32:
+		for i, x := range v {
+			buf = strconv.AppendFloat(separate(buf, i), float64(x), 'g',
+				a.precision, 32)
 		}
 	case []float64:
 		for i, x := range v {
-			writeVal(p.body, []byte(fmt.Sprintf("%f", x)))
-			if i < len(v)-1 {
-				writeSep(p.body)
-			}
+			buf = strconv.AppendFloat(separate(buf, i), x, 'g',
+				a.precision, 64)
 		}
 	default:
-		log.Fatalf("No binarise case for %T in asciier", v)
+		msg := "%w: %T in ascii format"
+		return nil, fmt.Errorf(msg, ErrUnsupportedType, data)
 	}
+	p.body.Write(buf)
 
 	// set header
 	if err := p.setHeader(); err != nil {
-		log.Fatalf("could not set header %v", err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// separate appends a space to buf for all but the first (i == 0) element.
+func separate(buf []byte, i int) []byte {
+	if i > 0 {
+		return append(buf, ' ')
 	}
-	return p
+	return buf
 }
 
 // Encode encodes the payload to []byte.
@@ -81,12 +124,8 @@ func (a asciier) format() string { return formatAscii }
 // base64er encodes the payload using standard base64 encoding.
 type base64er struct{}
 
-func (b base64er) binarise(data interface{}) *payload {
-	p, err := newPayloadFromData(data)
-	if err != nil {
-		log.Fatalf("Cannot convert data to payload: %v", err)
-	}
-	return p
+func (b base64er) binarise(data interface{}, header64 bool) (*payload, error) {
+	return newPayloadFromData(data, header64)
 }
 
 func (b base64er) encode(p *payload) ([]byte, error) {
@@ -125,12 +164,8 @@ func (b base64er) format() string { return formatBinary }
 // binaryer encodes the payload as raw binary data.
 type binaryer struct{}
 
-func (b binaryer) binarise(data interface{}) *payload {
-	p, err := newPayloadFromData(data)
-	if err != nil {
-		log.Fatalf("Cannot convert data to payload: %v", err)
-	}
-	return p
+func (b binaryer) binarise(data interface{}, header64 bool) (*payload, error) {
+	return newPayloadFromData(data, header64)
 }
 
 func (b binaryer) encode(p *payload) ([]byte, error) {
@@ -2,55 +2,53 @@ package govtk
 
 import (
 	"bytes"
+	"compress/zlib"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"io/ioutil"
 	"testing"
 )
 
 type pair struct {
-	val       []float64
-	str       []byte
-	hex       string
-	b64, b64c []byte
+	val []float64
+	str []byte
+	hex string
+	b64 []byte
 }
 
 // the test values are only for float64 now...
 // probably better to extend to more elaborate examples
 var pairs = []pair{
 	pair{
-		val:  []float64{1},
-		str:  []byte("1.000000"),
-		hex:  "000000000000f03f",
-		b64:  []byte("CAAAAAAAAAAAAPA/"),
-		b64c: []byte("AQAAAAgAAAAIAAAAEQAAAA==eJxiAIMP9oAAAAD//wInATA="),
+		val: []float64{1},
+		str: []byte("1"),
+		hex: "000000000000f03f",
+		b64: []byte("CAAAAAAAAAAAAPA/"),
 	},
 	pair{ // print -, dont print +
-		val:  []float64{-1, +1},
-		str:  []byte("-1.000000 1.000000"),
-		hex:  "000000000000f0bf000000000000f03f",
-		b64:  []byte("EAAAAAAAAAAAAPC/AAAAAAAA8D8="),
-		b64c: []byte("AQAAABAAAAAQAAAAEwAAAA==eJxiAIMP+6G0PSAAAP//EkYC3w=="),
+		val: []float64{-1, +1},
+		str: []byte("-1 1"),
+		hex: "000000000000f0bf000000000000f03f",
+		b64: []byte("EAAAAAAAAAAAAPC/AAAAAAAA8D8="),
 	},
 	pair{ // space before -
-		val:  []float64{1, -1},
-		str:  []byte("1.000000 -1.000000"),
-		hex:  "000000000000f03f000000000000f0bf",
-		b64:  []byte("EAAAAAAAAAAAAPA/AAAAAAAA8L8="),
-		b64c: []byte("AQAAABAAAAAQAAAAEwAAAA==eJxiAIMP9lB6PyAAAP//DkYC3w=="),
+		val: []float64{1, -1},
+		str: []byte("1 -1"),
+		hex: "000000000000f03f000000000000f0bf",
+		b64: []byte("EAAAAAAAAAAAAPA/AAAAAAAA8L8="),
 	},
 	pair{
-		val:  []float64{1, 2, 3},
-		str:  []byte("1.000000 2.000000 3.000000"),
-		hex:  "000000000000f03f00000000000000400000000000000840",
-		b64:  []byte("GAAAAAAAAAAAAPA/AAAAAAAAAEAAAAAAAAAIQA=="),
-		b64c: []byte("AQAAABgAAAAYAAAAGAAAAA==eJxiAIMP9hCawQFCcTgAAgAA//8XtwG4"),
+		val: []float64{1, 2, 3},
+		str: []byte("1 2 3"),
+		hex: "000000000000f03f00000000000000400000000000000840",
+		b64: []byte("GAAAAAAAAAAAAPA/AAAAAAAAAEAAAAAAAAAIQA=="),
 	},
 	pair{
-		val:  make([]float64, 3),
-		str:  []byte("0.000000 0.000000 0.000000"),
-		hex:  "000000000000000000000000000000000000000000000000",
-		b64:  []byte("GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=="),
-		b64c: []byte("AQAAABgAAAAYAAAADwAAAA==eJxiwAEAAQAA//8AGAAB"),
+		val: make([]float64, 3),
+		str: []byte("0 0 0"),
+		hex: "000000000000000000000000000000000000000000000000",
+		b64: []byte("GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=="),
 	},
 }
 
@@ -68,7 +66,10 @@ func TestBinarise(t *testing.T) {
 			t.Fatalf("Cannot setup reference values %v.", err)
 		}
 
-		p := enc.binarise(pair.val)
+		p, err := enc.binarise(pair.val, false)
+		if err != nil {
+			t.Fatalf("Binarise error %v", err)
+		}
 
 		// header content
 		got := p.head.Bytes()
@@ -89,11 +90,14 @@ func TestBinarise(t *testing.T) {
 	encoders := []encoder{base64er{}, binaryer{}}
 	for _, enc := range encoders {
 		for _, pair := range pairs {
-			p := enc.binarise(pair.val)
+			p, err := enc.binarise(pair.val, false)
+			if err != nil {
+				t.Fatalf("Binarise error %v", err)
+			}
 
 			buf.Reset()
 			n := int32(len(pair.val) * 8)
-			err := binary.Write(buf, binary.LittleEndian, n)
+			err = binary.Write(buf, binary.LittleEndian, n)
 			if err != nil {
 				t.Fatalf("Cannot setup reference values %v.", err)
 			}
@@ -123,7 +127,10 @@ func TestBinarise(t *testing.T) {
 func TestEncodeAscii(t *testing.T) {
 	enc := asciier{}
 	for _, pair := range pairs {
-		p := enc.binarise(pair.val)
+		p, err := enc.binarise(pair.val, false)
+		if err != nil {
+			t.Fatalf("Binarise error %v", err)
+		}
 		b, err := enc.encode(p)
 		if err != nil {
 			t.Errorf("Encoder error %v", err)
@@ -142,7 +149,7 @@ func TestEncodeBinary(t *testing.T) {
 
 	for _, c := range compressors {
 		for _, p := range pairs {
-			pl, err := c.compress(enc.binarise(p.val))
+			pl, err := c.compress(mustBinarise(t, enc, p.val))
 			if err != nil {
 				t.Errorf("Compress error %v", err)
 			}
@@ -167,7 +174,7 @@ func TestEncodeBase64(t *testing.T) {
 
 	c = noCompression{}
 	for _, p := range pairs {
-		pl, err := c.compress(enc.binarise(p.val))
+		pl, err := c.compress(mustBinarise(t, enc, p.val))
 		if err != nil {
 			t.Errorf("Compress error %v", err)
 		}
@@ -183,9 +190,11 @@ func TestEncodeBase64(t *testing.T) {
 		}
 	}
 
+	// the compressed bytes depend on the zlib implementation, therefore the
+	// header is verified and the body is decompressed
 	c = zlibCompression{level: DefaultCompression}
 	for _, p := range pairs {
-		pl, err := c.compress(enc.binarise(p.val))
+		pl, err := c.compress(mustBinarise(t, enc, p.val))
 		if err != nil {
 			t.Errorf("Compress error %v", err)
 		}
@@ -195,9 +204,125 @@ func TestEncodeBase64(t *testing.T) {
 			t.Errorf("Encoder error %v", err)
 		}
 
-		if !bytes.Equal(got, p.b64c) {
-			t.Errorf("Wrongly uncompressed base64 encoding: got: %v exp: %v",
-				string(got), string(p.b64))
+		// header and body are encoded separately
+		n := base64.StdEncoding.EncodedLen(pl.head.Len())
+		head, err := base64.StdEncoding.DecodeString(string(got[:n]))
+		if err != nil {
+			t.Fatalf("Cannot decode header: %v", err)
+		}
+		body, err := base64.StdEncoding.DecodeString(string(got[n:]))
+		if err != nil {
+			t.Fatalf("Cannot decode body: %v", err)
+		}
+
+		hdr := []uint32{1, DefaultBlockSize, uint32(8 * len(p.val)),
+			uint32(len(body))}
+		buf := new(bytes.Buffer)
+		if err := binary.Write(buf, binary.LittleEndian, hdr); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(head, buf.Bytes()) {
+			t.Errorf("Wrong compressed header: got %x exp %x", head, buf.Bytes())
+		}
+
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Cannot decompress body: %v", err)
+		}
+		data, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("Cannot decompress body: %v", err)
+		}
+		exp, _ := hex.DecodeString(p.hex)
+		if !bytes.Equal(data, exp) {
+			t.Errorf("Wrongly compressed base64 encoding: got: %x exp: %x",
+				data, exp)
+		}
+	}
+}
+
+// mustBinarise returns the binarised payload of data and fails the test
+// when the encoder returns an error.
+func mustBinarise(t *testing.T, enc encoder, data interface{}) *payload {
+	t.Helper()
+	p, err := enc.binarise(data, false)
+	if err != nil {
+		t.Fatalf("Binarise error %v", err)
+	}
+	return p
+}
+
+// Ensure unsupported data surfaces as an error instead of terminating.
+func TestBinariseUnsupported(t *testing.T) {
+	encoders := []encoder{asciier{}, base64er{}, binaryer{}}
+	for _, enc := range encoders {
+		_, err := enc.binarise("not numeric", false)
+		if err == nil {
+			t.Errorf("%T accepted unsupported data", enc)
+		}
+	}
+}
+
+// Ensure all numeric types are written in ascii format.
+func TestBinariseAsciiTypes(t *testing.T) {
+	tests := []struct {
+		val interface{}
+		str string
+	}{
+		{[]int8{-1, 2}, "-1 2"},
+		{[]uint8{1, 255}, "1 255"},
+		{[]int16{-300, 3}, "-300 3"},
+		{[]uint16{65535}, "65535"},
+		{[]int32{-7, 0}, "-7 0"},
+		{[]uint32{4294967295}, "4294967295"},
+		{[]int{-1, 1 << 40}, "-1 1099511627776"},
+		{[]uint{3}, "3"},
+		{[]int64{-1 << 40}, "-1099511627776"},
+		{[]uint64{1 << 63}, "9223372036854775808"},
+		{[]float32{0.1, -2.5}, "0.1 -2.5"},
+		{[]float64{1e-9, 1.5e20, 0.1}, "1e-09 1.5e+20 0.1"},
+		{int32(-7), "-7"},
+		{1, "1"},
+		{1.5, "1.5"},
+	}
+
+	enc := asciier{precision: -1}
+	for _, test := range tests {
+		p, err := enc.binarise(test.val, false)
+		if err != nil {
+			t.Errorf("%T: %v", test.val, err)
+			continue
+		}
+		if got := p.body.String(); got != test.str {
+			t.Errorf("%T: got %q exp %q", test.val, got, test.str)
+		}
+	}
+}
+
+// Ensure the precision limits the significant digits of floats.
+func TestBinariseAsciiPrecision(t *testing.T) {
+	data := []float64{1.0 / 3, 123456.789, 2e-8}
+
+	p, err := asciier{precision: 4}.binarise(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "0.3333 1.235e+05 2e-08"; p.body.String() != exp {
+		t.Errorf("got %q exp %q", p.body.String(), exp)
+	}
+
+	// shortest representation reads back exactly
+	p, err = asciier{precision: -1}.binarise(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := parseAscii("Float64", p.body.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values.([]float64) {
+		if v != data[i] {
+			t.Errorf("round trip: got %v exp %v", v, data[i])
 		}
 	}
 }
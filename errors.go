@@ -0,0 +1,32 @@
+package govtk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when adding or writing data. The errors are wrapped with
+// additional details, use errors.Is to match them.
+var (
+	// ErrUnsupportedType indicates data of a type that cannot be mapped
+	// onto a VTK data type or cannot be written in the requested format.
+	ErrUnsupportedType = errors.New("Unsupported data type")
+
+	// ErrEncoding indicates a failure while converting or encoding the
+	// data towards the format of the file.
+	ErrEncoding = errors.New("Encoding failed")
+
+	// ErrCompression indicates a failure while compressing the data.
+	ErrCompression = errors.New("Compression failed")
+
+	// ErrDuplicateName indicates an array name that is already in use.
+	ErrDuplicateName = errors.New("Duplicate array name")
+
+	// ErrInvalidMesh indicates an inconsistent mesh, see Header.Validate.
+	ErrInvalidMesh = errors.New("Invalid mesh")
+)
+
+// errHeaderOverflow indicates a payload header value that does not fit the
+// UInt32 header type, which requires promotion towards UInt64.
+var errHeaderOverflow = fmt.Errorf("%w: header value exceeds UInt32",
+	ErrEncoding)
@@ -1,5 +1,9 @@
 module github.com/maxvdkolk/govtk
 
-go 1.12
+go 1.13
 
-require golang.org/x/tools v0.0.0-20200313205530-4303120df7d8 // indirect
+require (
+	github.com/pierrec/lz4 v2.6.1+incompatible
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/tools v0.0.0-20200313205530-4303120df7d8 // indirect
+)
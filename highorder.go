@@ -0,0 +1,105 @@
+package govtk
+
+import "fmt"
+
+// Names of the cell and point data arrays of higher order cells.
+const (
+	higherOrderDegrees = "HigherOrderDegrees"
+	rationalWeights    = "RationalWeights"
+)
+
+// HigherOrderDegrees sets the polynomial degrees of the Lagrange and Bezier
+// cells of the unstructured grid, given as three values per cell, i.e. the
+// degree in each parametric direction. Unused directions, e.g. for curves or
+// simplices, can be given any value. The degrees are stored as cell data,
+// hence the Cells are required to be set first.
+//
+// Without degrees, VTK infers the degree from the number of nodes of each
+// cell, which requires equal degrees in all directions.
+func HigherOrderDegrees(degrees []int) Option {
+	return func(h *Header) error {
+		lp, err := h.lastPiece()
+		if err != nil {
+			return err
+		}
+		if lp.NumberOfCells == 0 {
+			return fmt.Errorf("Higher order degrees require cells to be set")
+		}
+		if len(degrees) != 3*lp.NumberOfCells {
+			msg := "Expected 3 degrees for each of the %d cells, got: %d"
+			return fmt.Errorf(msg, lp.NumberOfCells, len(degrees))
+		}
+
+		if err := h.cellData(higherOrderDegrees, degrees); err != nil {
+			return err
+		}
+		lp.CellData.HigherOrderDegrees = higherOrderDegrees
+		return nil
+	}
+}
+
+// RationalWeights sets the weights of the points of rational Bezier cells,
+// given as a single value per point. The weights are stored as point data,
+// hence the Points are required to be set first.
+func RationalWeights(weights []float64) Option {
+	return func(h *Header) error {
+		lp, err := h.lastPiece()
+		if err != nil {
+			return err
+		}
+		if lp.NumberOfPoints == 0 {
+			return fmt.Errorf("Rational weights require points to be set")
+		}
+		if len(weights) != lp.NumberOfPoints {
+			msg := "Expected a weight for each of the %d points, got: %d"
+			return fmt.Errorf(msg, lp.NumberOfPoints, len(weights))
+		}
+
+		if err := h.pointData(rationalWeights, weights); err != nil {
+			return err
+		}
+		lp.PointData.RationalWeights = rationalWeights
+		return nil
+	}
+}
+
+// higherOrderNodes returns the number of nodes of the Lagrange or Bezier cell
+// type for the given degrees, or -1 when the number of nodes is unknown.
+func higherOrderNodes(cellType int, p [3]int) int {
+	switch cellType {
+	case LagrangeCurve, BezierCurve:
+		return p[0] + 1
+	case LagrangeTriangle, BezierTriangle:
+		return (p[0] + 1) * (p[0] + 2) / 2
+	case LagrangeQuadrilateral, BezierQuadrilateral:
+		return (p[0] + 1) * (p[1] + 1)
+	case LagrangeTetrahedron, BezierTetrahedron:
+		return (p[0] + 1) * (p[0] + 2) * (p[0] + 3) / 6
+	case LagrangeHexahedron, BezierHexahedron:
+		return (p[0] + 1) * (p[1] + 1) * (p[2] + 1)
+	case LagrangeWedge, BezierWedge:
+		return (p[0] + 1) * (p[0] + 2) / 2 * (p[2] + 1)
+	}
+	return -1
+}
+
+// cellDegrees returns the degrees of the cells of the piece, or nil when no
+// HigherOrderDegrees are set.
+func cellDegrees(p *partition) ([]int, error) {
+	if p.CellData == nil || p.CellData.HigherOrderDegrees == "" {
+		return nil, nil
+	}
+	values, err := p.CellData.values(p.CellData.HigherOrderDegrees)
+	if err != nil {
+		return nil, err
+	}
+	degrees, err := toInts(values)
+	if err != nil {
+		return nil, err
+	}
+	if len(degrees) != 3*p.NumberOfCells {
+		msg := "Expected 3 degrees for each of the %d cells, got: %d"
+		return nil, fmt.Errorf(msg, p.NumberOfCells, len(degrees))
+	}
+	return degrees, nil
+}
@@ -0,0 +1,116 @@
+package govtk
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newHigherOrder returns a bi-quadratic Lagrange quadrilateral and a
+// quadratic rational Bezier curve sharing the points of the quadrilateral.
+func newHigherOrder(t *testing.T, degrees []int) *Header {
+	vtu, err := Unstructured(Raw())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var coords []float64
+	for j := 0; j < 3; j++ {
+		for i := 0; i < 3; i++ {
+			coords = append(coords, 0.5*float64(i), 0.5*float64(j), 0)
+		}
+	}
+	conn := []int{0, 2, 8, 6, 1, 5, 7, 3, 4, 0, 2, 1}
+	offset := []int{0, 9, 12}
+	types := []int{LagrangeQuadrilateral, BezierCurve}
+
+	err = vtu.Add(Points(coords), Cells(conn, offset, types),
+		HigherOrderDegrees(degrees),
+		RationalWeights([]float64{1, 0.7, 1, 1, 1, 1, 1, 1, 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vtu
+}
+
+func TestHigherOrder(t *testing.T) {
+	vtu := newHigherOrder(t, []int{2, 2, 0, 2, 0, 0})
+	if err := vtu.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := vtu.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<CellData HigherOrderDegrees="HigherOrderDegrees">`,
+		`<PointData RationalWeights="RationalWeights">`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Missing %s in output", s)
+		}
+	}
+
+	// the attributes are restored when reading
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := r.Grid.Pieces[0]
+	if p.CellData.HigherOrderDegrees != higherOrderDegrees ||
+		p.PointData.RationalWeights != rationalWeights {
+		t.Errorf("Attributes not restored: %+v %+v", p.CellData.attributes,
+			p.PointData.attributes)
+	}
+	if err := r.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// degrees not matching the number of nodes
+	vtu = newHigherOrder(t, []int{2, 1, 0, 2, 0, 0})
+	if err := vtu.Validate(); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("Expected invalid mesh, got: %v", err)
+	}
+
+	// degrees and weights should match the cells and points
+	if err := vtu.Add(HigherOrderDegrees([]int{2, 2, 0})); err == nil {
+		t.Errorf("Expected error for missing degrees")
+	}
+	if err := vtu.Add(RationalWeights([]float64{1})); err == nil {
+		t.Errorf("Expected error for missing weights")
+	}
+
+	// degrees and weights require the cells and points to be set first
+	empty, err := Unstructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.Add(HigherOrderDegrees([]int{})); err == nil {
+		t.Errorf("Expected error for degrees without cells")
+	}
+	if err := empty.Add(RationalWeights([]float64{})); err == nil {
+		t.Errorf("Expected error for weights without points")
+	}
+}
+
+func TestHigherOrderNodes(t *testing.T) {
+	tests := []struct {
+		cellType int
+		degrees  [3]int
+		nodes    int
+	}{
+		{LagrangeCurve, [3]int{3, 0, 0}, 4},
+		{BezierTriangle, [3]int{2, 2, 0}, 6},
+		{LagrangeQuadrilateral, [3]int{2, 3, 0}, 12},
+		{LagrangeTetrahedron, [3]int{2, 2, 2}, 10},
+		{BezierHexahedron, [3]int{2, 2, 2}, 27},
+		{LagrangeWedge, [3]int{2, 2, 1}, 12},
+		{LagrangePyramid, [3]int{2, 2, 2}, -1},
+	}
+	for _, test := range tests {
+		if n := higherOrderNodes(test.cellType, test.degrees); n != test.nodes {
+			t.Errorf("Wrong number of nodes for type %d: got %d, exp %d",
+				test.cellType, n, test.nodes)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package govtk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Identifier of the legacy file format, written on the first line.
+const legacyVersion = "# vtk DataFile Version 3.0"
+
+// Legacy writes the header in the legacy (*.vtk) format instead of XML. The
+// data is written as ASCII for Ascii(), otherwise as big-endian BINARY.
+// Compression and appended data do not apply to the legacy format, neither
+// do polyhedron cells, see PolyhedronCells().
+func Legacy() Option {
+	return func(h *Header) error {
+		h.legacy = true
+		return nil
+	}
+}
+
+// legacyWriter writes the legacy format towards the io.Writer. The first
+// error encountered is kept and all subsequent writes are skipped, such that
+// the error only needs to be checked once after writing.
+type legacyWriter struct {
+	w     io.Writer
+	ascii bool
+	err   error
+}
+
+// printf writes the formatted string to the underlying writer.
+func (lw *legacyWriter) printf(format string, a ...interface{}) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = fmt.Fprintf(lw.w, format, a...)
+}
+
+// values writes the data as space separated ascii values or as big-endian
+// binary data. Both are terminated by a newline.
+func (lw *legacyWriter) values(data interface{}) {
+	if lw.err != nil {
+		return
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		s := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+		s.Index(0).Set(v)
+		v = s
+	}
+
+	if lw.ascii {
+		for i := 0; i < v.Len(); i++ {
+			sep := " "
+			if i == v.Len()-1 {
+				sep = "\n"
+			}
+			lw.printf("%v%s", v.Index(i).Interface(), sep)
+		}
+		return
+	}
+
+	lw.err = binary.Write(lw.w, binary.BigEndian, fixedSize(v.Interface()))
+	lw.printf("\n")
+}
+
+// cells writes the cells in the legacy layout, where each cell is given by
+// its number of points followed by the point indices. The offsets indicate
+// the end of each cell in conn.
+func (lw *legacyWriter) cells(keyword string, conn, offsets []int) {
+	if len(offsets) == 0 {
+		return
+	}
+	lw.printf("%s %d %d\n", keyword, len(offsets), len(conn)+len(offsets))
+
+	// one cell per line for ascii, a single block for binary
+	data := make([]int, 0, len(conn)+len(offsets))
+	start := 0
+	for _, end := range offsets {
+		if lw.ascii {
+			lw.values(append([]int{end - start}, conn[start:end]...))
+		} else {
+			data = append(data, end-start)
+			data = append(data, conn[start:end]...)
+		}
+		start = end
+	}
+
+	if !lw.ascii {
+		lw.values(int32s(data))
+	}
+}
+
+// int32s converts the integers towards int32 as required for the cells and
+// cell types of the legacy format.
+func int32s(data []int) []int32 {
+	res := make([]int32, len(data))
+	for i, x := range data {
+		res[i] = int32(x)
+	}
+	return res
+}
+
+// fields writes all arrays of the data array as legacy field data.
+func (lw *legacyWriter) fields(name string, da *dataArray) {
+	if da == nil || len(da.Data) == 0 {
+		return
+	}
+
+	lw.printf("FIELD %s %d\n", name, len(da.Data))
+	for _, arr := range da.Data {
+		values, err := da.decode(arr)
+		if err != nil {
+			lw.err = err
+			return
+		}
+		dtype, err := legacyType(values)
+		if err != nil {
+			lw.err = err
+			return
+		}
+
+		n := 1
+		if v := reflect.ValueOf(values); v.Kind() == reflect.Slice {
+			n = v.Len()
+		}
+
+		nc := arr.NumberOfComponents
+		if nc == 0 {
+			nc = 1
+		}
+
+		// legacy names cannot contain spaces
+		label := strings.Replace(arr.Name, " ", "%20", -1)
+		lw.printf("%s %d %d %s\n", label, nc, n/nc, dtype)
+		lw.values(values)
+	}
+}
+
+// legacyType returns the legacy type name of the data.
+func legacyType(data interface{}) (string, error) {
+	switch data.(type) {
+	case int8, []int8:
+		return "char", nil
+	case uint8, []uint8:
+		return "unsigned_char", nil
+	case int16, []int16:
+		return "short", nil
+	case uint16, []uint16:
+		return "unsigned_short", nil
+	case int32, []int32:
+		return "int", nil
+	case uint32, []uint32:
+		return "unsigned_int", nil
+	case int, int64, []int, []int64:
+		return "vtktypeint64", nil
+	case uint, uint64, []uint, []uint64:
+		return "vtktypeuint64", nil
+	case float32, []float32:
+		return "float", nil
+	case float64, []float64:
+		return "double", nil
+	}
+	msg := "%w: cannot map data %v (%T) to legacy type"
+	return "", fmt.Errorf(msg, ErrUnsupportedType, data, data)
+}
+
+// dimensions returns the number of points in each direction of the bounds.
+func (b bounds) dimensions() [3]int {
+	return [3]int{b[1] - b[0] + 1, b[3] - b[2] + 1, b[5] - b[4] + 1}
+}
+
+// parseTriple parses a string of three floats as written by Origin and
+// Spacing. An empty string results in the provided default values.
+func parseTriple(s string, def [3]float64) ([3]float64, error) {
+	if s == "" {
+		return def, nil
+	}
+	var v [3]float64
+	_, err := fmt.Sscan(s, &v[0], &v[1], &v[2])
+	return v, err
+}
+
+// writeLegacy writes the header in the legacy format. The legacy format only
+// holds a single piece.
+func (h *Header) writeLegacy(w io.Writer) error {
+	if len(h.Grid.Pieces) > 1 {
+		msg := "Legacy format supports a single piece, got: %d"
+		return fmt.Errorf(msg, len(h.Grid.Pieces))
+	}
+
+	var lp partition
+	if len(h.Grid.Pieces) == 1 {
+		lp = h.Grid.Pieces[0]
+	} else {
+		lp.NumberOfPoints = h.Grid.Extent.numPoints()
+		lp.NumberOfCells = h.Grid.Extent.numCells()
+	}
+
+	lw := &legacyWriter{w: w, ascii: h.format == formatAscii}
+	lw.printf("%s\ngovtk %s\n", legacyVersion, h.Type)
+	if lw.ascii {
+		lw.printf("ASCII\n")
+	} else {
+		lw.printf("BINARY\n")
+	}
+
+	var err error
+	switch h.Type {
+	case imageData:
+		err = h.legacyStructuredPoints(lw)
+	case rectilinearGrid:
+		err = h.legacyRectilinearGrid(lw, lp)
+	case structuredGrid:
+		lw.printf("DATASET STRUCTURED_GRID\n")
+		d := h.Grid.Extent.dimensions()
+		lw.printf("DIMENSIONS %d %d %d\n", d[0], d[1], d[2])
+		err = legacyPoints(lw, lp)
+	case unstructuredGrid:
+		lw.printf("DATASET UNSTRUCTURED_GRID\n")
+		if err = legacyPoints(lw, lp); err == nil {
+			err = legacyUnstructuredCells(lw, lp)
+		}
+	case polyData:
+		lw.printf("DATASET POLYDATA\n")
+		if err = legacyPoints(lw, lp); err == nil {
+			err = legacyPolyCells(lw, lp)
+		}
+	default:
+		err = fmt.Errorf("Legacy format not available for %s", h.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	lw.fields("FieldData", h.Grid.Data)
+
+	if lp.CellData != nil {
+		lw.printf("CELL_DATA %d\n", lp.NumberOfCells)
+		lw.fields("FieldData", lp.CellData)
+	}
+	if lp.PointData != nil {
+		lw.printf("POINT_DATA %d\n", lp.NumberOfPoints)
+		lw.fields("FieldData", lp.PointData)
+	}
+	return lw.err
+}
+
+// legacyStructuredPoints writes the geometry of image data. The origin is
+// shifted towards the lower bound of the extent, as the legacy format does
+// not store the extent itself.
+func (h *Header) legacyStructuredPoints(lw *legacyWriter) error {
+	origin, err := parseTriple(h.Grid.Origin, [3]float64{0, 0, 0})
+	if err != nil {
+		return err
+	}
+	spacing, err := parseTriple(h.Grid.Spacing, [3]float64{1, 1, 1})
+	if err != nil {
+		return err
+	}
+
+	b := h.Grid.Extent
+	for i := range origin {
+		origin[i] += float64(b[2*i]) * spacing[i]
+	}
+
+	d := b.dimensions()
+	lw.printf("DATASET STRUCTURED_POINTS\n")
+	lw.printf("DIMENSIONS %d %d %d\n", d[0], d[1], d[2])
+	lw.printf("ORIGIN %v %v %v\n", origin[0], origin[1], origin[2])
+	lw.printf("SPACING %v %v %v\n", spacing[0], spacing[1], spacing[2])
+	return nil
+}
+
+// legacyRectilinearGrid writes the coordinates of the rectilinear grid.
+// Missing coordinates are written as a single zero.
+func (h *Header) legacyRectilinearGrid(lw *legacyWriter, lp partition) error {
+	d := h.Grid.Extent.dimensions()
+	lw.printf("DATASET RECTILINEAR_GRID\n")
+	lw.printf("DIMENSIONS %d %d %d\n", d[0], d[1], d[2])
+
+	for i, dim := range []string{"x", "y", "z"} {
+		key := strings.ToUpper(dim) + "_COORDINATES"
+
+		var arr *darray
+		if lp.Coordinates != nil {
+			arr = lp.Coordinates.lookup(dim + "_coordinates")
+		}
+		if arr == nil {
+			lw.printf("%s %d double\n", key, 1)
+			lw.values([]float64{0})
+			continue
+		}
+
+		values, err := lp.Coordinates.decode(arr)
+		if err != nil {
+			return err
+		}
+		dtype, err := legacyType(values)
+		if err != nil {
+			return err
+		}
+		lw.printf("%s %d %s\n", key, d[i], dtype)
+		lw.values(values)
+	}
+	return nil
+}
+
+// legacyPoints writes the points of the piece.
+func legacyPoints(lw *legacyWriter, lp partition) error {
+	if lp.Points == nil {
+		return fmt.Errorf("Legacy format requires points to be set")
+	}
+
+	values, err := lp.Points.values("Points")
+	if err != nil {
+		return err
+	}
+	dtype, err := legacyType(values)
+	if err != nil {
+		return err
+	}
+	lw.printf("POINTS %d %s\n", lp.NumberOfPoints, dtype)
+	lw.values(values)
+	return nil
+}
+
+// legacyUnstructuredCells writes the cells and cell types of the piece.
+func legacyUnstructuredCells(lw *legacyWriter, lp partition) error {
+	if lp.Cells == nil {
+		return nil
+	}
+
+	conn, offsets, err := cellArrays(lp.Cells)
+	if err != nil {
+		return err
+	}
+	values, err := lp.Cells.values("types")
+	if err != nil {
+		return err
+	}
+	types, err := toInts(values)
+	if err != nil {
+		return err
+	}
+	for i, t := range types {
+		if t == Polyhedron {
+			msg := "Legacy format cannot write polyhedron cell %d"
+			return fmt.Errorf(msg, i)
+		}
+	}
+
+	lw.cells("CELLS", conn, offsets)
+	lw.printf("CELL_TYPES %d\n", len(types))
+	lw.values(int32s(types))
+	return nil
+}
+
+// legacyPolyCells writes the vertices, lines, polygons, and triangle strips
+// of the piece.
+func legacyPolyCells(lw *legacyWriter, lp partition) error {
+	groups := []struct {
+		keyword string
+		da      *dataArray
+	}{
+		{"VERTICES", lp.Verts},
+		{"LINES", lp.Lines},
+		{"POLYGONS", lp.Polys},
+		{"TRIANGLE_STRIPS", lp.Strips},
+	}
+
+	for _, g := range groups {
+		if g.da == nil {
+			continue
+		}
+		conn, offsets, err := cellArrays(g.da)
+		if err != nil {
+			return err
+		}
+		lw.cells(g.keyword, conn, offsets)
+	}
+	return nil
+}
+
+// cellArrays returns the connectivity and offsets of the data array as
+// integer slices. The offsets exclude the leading zero, independent of the
+// format version.
+func cellArrays(da *dataArray) ([]int, []int, error) {
+	res := make([][]int, 2)
+	for i, name := range []string{"connectivity", "offsets"} {
+		values, err := da.values(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if res[i], err = toInts(values); err != nil {
+			return nil, nil, err
+		}
+	}
+	if da.leadingZero && len(res[1]) > 0 {
+		res[1] = res[1][1:]
+	}
+	return res[0], res[1], nil
+}
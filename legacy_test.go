@@ -0,0 +1,165 @@
+package govtk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestLegacyUnstructuredAscii(t *testing.T) {
+	vtu := newTetra(t, Ascii(), Legacy())
+	if err := vtu.Add(FieldData("time", []float64{0.5})); err != nil {
+		t.Fatal(err)
+	}
+	if vtu.FileExtension() != "vtk" {
+		t.Errorf("Wrong extension: got %v, exp %v", vtu.FileExtension(), "vtk")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := vtu.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `# vtk DataFile Version 3.0
+govtk UnstructuredGrid
+ASCII
+DATASET UNSTRUCTURED_GRID
+POINTS 4 double
+0 0 0 1 0 0 0 1 0 0 0 1
+CELLS 1 5
+4 0 1 2 3
+CELL_TYPES 1
+10
+FIELD FieldData 1
+time 1 1 double
+0.5
+CELL_DATA 1
+FIELD FieldData 1
+velocity 3 1 double
+1 0 0
+POINT_DATA 4
+FIELD FieldData 1
+temperature 1 4 double
+1 2 3 4
+`
+	if buf.String() != exp {
+		t.Errorf("Wrong legacy output: got:\n%s\nexp:\n%s", buf.String(), exp)
+	}
+}
+
+func TestLegacyImageBinary(t *testing.T) {
+	img, err := Image(WholeExtent(1, 2, 0, 1, 0, 0), Spacing(0.5, 1, 1),
+		Legacy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := img.Add(PointData("f", []int32{1, 2, 3, 4})); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := img.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	head := `# vtk DataFile Version 3.0
+govtk ImageData
+BINARY
+DATASET STRUCTURED_POINTS
+DIMENSIONS 2 2 1
+ORIGIN 0.5 0 0
+SPACING 0.5 1 1
+POINT_DATA 4
+FIELD FieldData 1
+f 1 4 int
+`
+	if !strings.HasPrefix(buf.String(), head) {
+		t.Fatalf("Wrong legacy header: got:\n%s\nexp:\n%s", buf.String(), head)
+	}
+
+	vals := make([]int32, 4)
+	r := bytes.NewReader(buf.Bytes()[len(head):])
+	if err := binary.Read(r, binary.BigEndian, vals); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vals {
+		if v != int32(i+1) {
+			t.Errorf("Wrong big-endian value: got %v, exp %v", v, i+1)
+		}
+	}
+}
+
+func TestLegacyGrids(t *testing.T) {
+	vtr, _ := Rectilinear(WholeExtent(0, 1, 0, 2, 0, 0), Ascii(), Legacy())
+	if err := vtr.Add(Points([]float64{0, 1}, []float64{0, 1, 2})); err != nil {
+		t.Fatal(err)
+	}
+
+	vtp, _ := PolyData(Ascii(), Legacy())
+	err := vtp.Add(
+		Points([]float64{0, 0, 0, 1, 0, 0, 1, 1, 0}),
+		Verts([]int{0}, []int{0, 1}),
+		Polys([]int{0, 1, 2}, []int{0, 3}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		h   *Header
+		exp []string
+	}{
+		{vtr, []string{"DATASET RECTILINEAR_GRID\nDIMENSIONS 2 3 1\n",
+			"X_COORDINATES 2 double\n0 1\n",
+			"Y_COORDINATES 3 double\n0 1 2\n",
+			"Z_COORDINATES 1 double\n0\n"}},
+		{vtp, []string{"DATASET POLYDATA\nPOINTS 3 double\n",
+			"VERTICES 1 2\n1 0\n", "POLYGONS 1 4\n3 0 1 2\n"}},
+	}
+	for _, c := range cases {
+		buf := new(bytes.Buffer)
+		if err := c.h.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range c.exp {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("Missing %q in:\n%s", s, buf.String())
+			}
+		}
+	}
+
+	// multiple pieces cannot be written
+	vtu, _ := Unstructured(Legacy())
+	vtu.Add(Piece(), Piece())
+	if err := vtu.Write(new(bytes.Buffer)); err == nil {
+		t.Error("Legacy with multiple pieces should return error")
+	}
+}
+
+func TestLegacyReusedBuffer(t *testing.T) {
+	vtu := newTetra(t, Ascii(), Legacy())
+
+	// the data is encoded when added, such that the buffer can be reused
+	buf := []float64{1, 1, 1, 1}
+	if err := vtu.Add(PointData("a", buf)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range buf {
+		buf[i] = 9
+	}
+	if err := vtu.Add(PointData("b", buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := vtu.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"a 1 4 double\n1 1 1 1\n",
+		"b 1 4 double\n9 9 9 9\n"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Missing %q in:\n%s", s, out.String())
+		}
+	}
+}
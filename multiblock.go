@@ -0,0 +1,186 @@
+package govtk
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// MultiBlock represents a VTK multiblock data set (.vtm). The data set is a
+// tree of named blocks, where each leaf refers to a VTK XML file on disk.
+// Similar to the PVD collection, the leaf files are written into a single
+// directory, while the tree of blocks is kept in the multiblock file.
+type MultiBlock struct {
+	XMLName   xml.Name `xml:"VTKFile"`
+	Type      string   `xml:"type,attr"`
+	Version   float64  `xml:"version,attr"`
+	ByteOrder string   `xml:"byte_order,attr"`
+
+	// Root is the top level vtkMultiBlockDataSet element.
+	Root *Block
+
+	// All leaf files of the multiblock are written into this directory.
+	dir string
+
+	// Formatting string for the automatic file naming of the leaves.
+	filenameFormat string
+
+	// Number of leaf files written so far, used to number the files.
+	files int
+
+	// On true syncs the multiblock file, as any of its leaves is synced.
+	sync bool
+}
+
+// Block is a node in the tree of the multiblock data set. A block either
+// holds further blocks, or refers to a single file as a DataSet leaf.
+type Block struct {
+	XMLName xml.Name
+
+	// Index holds a pointer to int, as the root element has no index,
+	// while we do not want to consider Index = 0 as an empty value.
+	Index *int   `xml:"index,attr,omitempty"`
+	Name  string `xml:"name,attr,omitempty"`
+	File  string `xml:"file,attr,omitempty"`
+
+	// Blocks holds the child blocks and data sets of this block.
+	Blocks []*Block
+
+	// mb refers to the multiblock data set this block belongs to.
+	mb *MultiBlock
+}
+
+// Options for the multiblock data set.
+type MultiBlockOption func(mb *MultiBlock) error
+
+// NewMultiBlock initialises an empty multiblock data set with options.
+func NewMultiBlock(opts ...MultiBlockOption) (*MultiBlock, error) {
+	mb := &MultiBlock{
+		Type:      "vtkMultiBlockDataSet",
+		Version:   1.0,
+		ByteOrder: "LittleEndian",
+	}
+	mb.Root = &Block{
+		XMLName: xml.Name{Local: "vtkMultiBlockDataSet"},
+		mb:      mb,
+	}
+
+	defaults := []MultiBlockOption{
+		BlockDirectory("."),
+		BlockFileFormat("block_%03d.%s"),
+	}
+	opts = append(defaults, opts...)
+
+	for _, opt := range opts {
+		if err := opt(mb); err != nil {
+			return nil, err
+		}
+	}
+	return mb, nil
+}
+
+// BlockDirectory sets the directory to store the files of the multiblock.
+func BlockDirectory(dir string) MultiBlockOption {
+	return func(mb *MultiBlock) error {
+		if err := ensureDir(dir); err != nil {
+			return err
+		}
+		mb.dir = dir
+		return nil
+	}
+}
+
+// BlockFileFormat sets the formatting string for the automatic file naming.
+// This requires a single %d for the file number and a %s for the extension.
+func BlockFileFormat(format string) MultiBlockOption {
+	return func(mb *MultiBlock) error {
+		mb.filenameFormat = format
+		return nil
+	}
+}
+
+// Dir returns the directory of the multiblock data set.
+func (mb *MultiBlock) Dir() string {
+	return mb.dir
+}
+
+// Block returns the top level block with the given name. The block is
+// created when not present yet.
+func (mb *MultiBlock) Block(name string) *Block {
+	return mb.Root.Block(name)
+}
+
+// Add writes the header to file and inserts it as top level data set.
+func (mb *MultiBlock) Add(name string, h *Header) error {
+	return mb.Root.Add(name, h)
+}
+
+// Block returns the child block with the given name. The block is created
+// when not present yet.
+func (b *Block) Block(name string) *Block {
+	for _, c := range b.Blocks {
+		if c.XMLName.Local == "Block" && c.Name == name {
+			return c
+		}
+	}
+
+	c := &Block{
+		XMLName: xml.Name{Local: "Block"},
+		Index:   b.nextIndex(),
+		Name:    name,
+		mb:      b.mb,
+	}
+	b.Blocks = append(b.Blocks, c)
+	return c
+}
+
+// Add writes the header to a file inside the multiblock's directory and
+// inserts a data set referring to this file into the block.
+func (b *Block) Add(name string, h *Header) error {
+	mb := b.mb
+	filename := fmt.Sprintf(mb.filenameFormat, mb.files, h.FileExtension())
+
+	path := filepath.Join(mb.Dir(), filename)
+	if err := saveFile(path, h.sync, h.Write); err != nil {
+		return err
+	}
+	mb.files++
+	mb.sync = mb.sync || h.sync
+
+	b.Blocks = append(b.Blocks, &Block{
+		XMLName: xml.Name{Local: "DataSet"},
+		Index:   b.nextIndex(),
+		Name:    name,
+		File:    filename,
+		mb:      mb,
+	})
+	return nil
+}
+
+// nextIndex returns a pointer to the index of the next child of the block.
+func (b *Block) nextIndex() *int {
+	idx := new(int)
+	*idx = len(b.Blocks)
+	return idx
+}
+
+// Write writes the multiblock as encoded XML to the provided io.Writer.
+func (mb *MultiBlock) Write(w io.Writer) error {
+	_, err := w.Write([]byte(xml.Header))
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(mb)
+}
+
+// Save opens a file and writes the XML to file. The file names of the leaves
+// are relative to the multiblock's directory, hence the multiblock file is
+// typically saved inside Dir(). The multiblock file is synced to disk when
+// any of the leaves is, see Sync().
+func (mb *MultiBlock) Save(filename string) error {
+	if filepath.Ext(filename) == "" {
+		filename += ".vtm"
+	}
+	return saveFile(filename, mb.sync, mb.Write)
+}
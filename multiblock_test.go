@@ -0,0 +1,83 @@
+package govtk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mb, err := NewMultiBlock(BlockDirectory(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mb.Dir() != dir {
+		t.Errorf("Wrong directory: got %v, exp %v", mb.Dir(), dir)
+	}
+
+	img, _ := Image(WholeExtent(0, 1, 0, 1, 0, 1))
+
+	if err := mb.Block("fluid").Add("mesh", newTetra(t)); err != nil {
+		t.Fatal(err)
+	}
+	if mb.sync {
+		t.Error("Multiblock synced without synced leaves")
+	}
+	if err := mb.Block("solid").Add("mesh", newTetra(t, Sync())); err != nil {
+		t.Fatal(err)
+	}
+	if !mb.sync {
+		t.Error("Multiblock not synced with synced leaves")
+	}
+	patches := mb.Block("boundary")
+	if err := patches.Block("inlet").Add("patch", newTetra(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := patches.Block("outlet").Add("patch", newTetra(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mb.Add("background", img); err != nil {
+		t.Fatal(err)
+	}
+
+	// existing blocks are reused
+	if mb.Block("fluid") != mb.Root.Blocks[0] {
+		t.Error("Block with existing name should be reused")
+	}
+
+	if err := mb.Save(filepath.Join(dir, "case")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"case.vtm", "block_000.vtu",
+		"block_003.vtu", "block_004.vti"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Missing file: %v", err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "case.vtm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<VTKFile type="vtkMultiBlockDataSet"`,
+		`<vtkMultiBlockDataSet><Block index="0" name="fluid">`,
+		`<DataSet index="0" name="mesh" file="block_000.vtu">`,
+		`<Block index="2" name="boundary"><Block index="0" name="inlet">`,
+		`<Block index="1" name="outlet"><DataSet index="0" name="patch" file="block_003.vtu">`,
+		`<DataSet index="3" name="background" file="block_004.vti">`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("Missing %s in %s", s, b)
+		}
+	}
+}
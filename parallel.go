@@ -0,0 +1,292 @@
+package govtk
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Parallel represents the summary file of a parallel VTK XML data set, i.e.
+// the .pvti, .pvtr, .pvts, .pvtu, and .pvtp formats. The data set is split
+// into pieces, e.g. one per process or goroutine, that are each written to
+// their own file. The summary declares the arrays present in every piece and
+// refers to the files of all pieces by their Source attribute.
+type Parallel struct {
+	XMLName    xml.Name `xml:"VTKFile"`
+	Type       string   `xml:"type,attr"`
+	Version    float64  `xml:"version,attr"`
+	ByteOrder  string   `xml:"byte_order,attr"`
+	HeaderType string   `xml:"header_type,attr,omitempty"`
+	Grid       pgrid
+
+	// pieces holds the headers written to the individual piece files.
+	pieces []*Header
+
+	// prefix is used to name the piece files as `prefix_%d.ext`.
+	prefix string
+}
+
+// pgrid is the parallel counterpart of Grid. Instead of the data itself, it
+// only declares the data arrays and the pieces.
+type pgrid struct {
+	XMLName      xml.Name
+	GhostLevel   int         `xml:"GhostLevel,attr"`
+	Extent       *bounds     `xml:"WholeExtent,attr,omitempty"`
+	Origin       string      `xml:"Origin,attr,omitempty"`
+	Spacing      string      `xml:"Spacing,attr,omitempty"`
+	PPointData   *pdataArray `xml:",omitempty"`
+	PCellData    *pdataArray `xml:",omitempty"`
+	PPoints      *pdataArray `xml:",omitempty"`
+	PCoordinates *pdataArray `xml:",omitempty"`
+	Pieces       []ppiece
+}
+
+// pdataArray holds the declarations of a set of data arrays.
+type pdataArray struct {
+	Data []*pdarray
+}
+
+// pdarray declares the type, name, and number of components of a data array
+// without containing any data. The range covers all pieces, see DataRange.
+type pdarray struct {
+	XMLName            xml.Name `xml:"PDataArray"`
+	Type               string   `xml:"type,attr,omitempty"`
+	Name               string   `xml:"Name,attr,omitempty"`
+	NumberOfComponents int      `xml:"NumberOfComponents,attr,omitempty"`
+	RangeMin           *float64 `xml:"RangeMin,attr,omitempty"`
+	RangeMax           *float64 `xml:"RangeMax,attr,omitempty"`
+}
+
+// ppiece refers to the file of a single piece. The extent is only set for
+// image, rectilinear, and structured grids.
+type ppiece struct {
+	XMLName xml.Name `xml:"Piece"`
+	Extent  *bounds  `xml:"Extent,attr,omitempty"`
+	Source  string   `xml:"Source,attr"`
+}
+
+// NewParallel combines the headers into a parallel data set. All headers are
+// required to be of the same format, to be encoded alike, and to hold a
+// single piece with the same point and cell data arrays. The legacy format
+// has no parallel counterpart. For image, rectilinear, and structured grids
+// each header's WholeExtent denotes the extent of that piece; the whole
+// extent of the parallel data set is obtained as the union of the pieces.
+func NewParallel(pieces ...*Header) (*Parallel, error) {
+	if len(pieces) == 0 {
+		return nil, fmt.Errorf("Parallel data requires at least one piece")
+	}
+
+	first := pieces[0]
+	p := &Parallel{
+		Type:       "P" + first.Type,
+		Version:    first.Version,
+		ByteOrder:  first.ByteOrder,
+		HeaderType: first.HeaderType,
+		Grid:       pgrid{XMLName: xml.Name{Local: "P" + first.Type}},
+		pieces:     pieces,
+		prefix:     "piece",
+	}
+
+	for i, h := range pieces {
+		if h.legacy {
+			return nil, fmt.Errorf("Piece %d uses the legacy format", i)
+		}
+		if h.Type != first.Type {
+			msg := "Piece %d has format %s, exp: %s"
+			return nil, fmt.Errorf(msg, i, h.Type, first.Type)
+		}
+		if len(h.Grid.Pieces) == 0 {
+			return nil, fmt.Errorf("Piece %d contains no data", i)
+		}
+		if len(h.Grid.Pieces) > 1 {
+			msg := "Piece %d holds %d pieces, exp: a single piece per header"
+			return nil, fmt.Errorf(msg, i, len(h.Grid.Pieces))
+		}
+		if err := sameEncoding(first, h); err != nil {
+			return nil, fmt.Errorf("Piece %d: %v", i, err)
+		}
+		if err := sameDeclarations(first, h); err != nil {
+			return nil, fmt.Errorf("Piece %d: %v", i, err)
+		}
+	}
+
+	switch first.Type {
+	case imageData:
+		for i, h := range pieces {
+			if h.Grid.Origin != first.Grid.Origin ||
+				h.Grid.Spacing != first.Grid.Spacing {
+				msg := "Piece %d has different origin or spacing"
+				return nil, fmt.Errorf(msg, i)
+			}
+		}
+		p.Grid.Origin = first.Grid.Origin
+		p.Grid.Spacing = first.Grid.Spacing
+		fallthrough
+	case rectilinearGrid, structuredGrid:
+		whole := first.Grid.Extent
+		for _, h := range pieces {
+			b := h.Grid.Extent
+			for j := 0; j < len(b); j += 2 {
+				if b[j] < whole[j] {
+					whole[j] = b[j]
+				}
+				if b[j+1] > whole[j+1] {
+					whole[j+1] = b[j+1]
+				}
+			}
+		}
+		p.Grid.Extent = &whole
+	}
+
+	lp := first.Grid.Pieces[0]
+	p.Grid.PPointData = declare(lp.PointData)
+	p.Grid.PCellData = declare(lp.CellData)
+	p.Grid.PPoints = declare(lp.Points)
+	p.Grid.PCoordinates = declare(lp.Coordinates)
+
+	declareRanges(p.Grid.PPointData, pieces,
+		func(p *partition) *dataArray { return p.PointData })
+	declareRanges(p.Grid.PCellData, pieces,
+		func(p *partition) *dataArray { return p.CellData })
+
+	p.setSources()
+	return p, nil
+}
+
+// declare returns the declarations of all data arrays in the dataArray, or
+// nil when no dataArray is provided.
+func declare(da *dataArray) *pdataArray {
+	if da == nil {
+		return nil
+	}
+
+	pda := &pdataArray{}
+	for _, arr := range da.Data {
+		pda.Data = append(pda.Data, &pdarray{
+			Type:               arr.Type,
+			Name:               arr.Name,
+			NumberOfComponents: arr.NumberOfComponents,
+		})
+	}
+	return pda
+}
+
+// sameEncoding returns an error when both headers are not written with the
+// same version, header type, data format, and compression, as the summary
+// declares these once for all pieces.
+func sameEncoding(a, b *Header) error {
+	switch {
+	case a.Version != b.Version:
+		return fmt.Errorf("Version %v differs from %v", b.Version, a.Version)
+	case a.header64 != b.header64:
+		msg := "Header type %s differs from %s"
+		return fmt.Errorf(msg, b.HeaderType, a.HeaderType)
+	case a.format != b.format || (a.Appended == nil) != (b.Appended == nil):
+		return fmt.Errorf("Data format differs from the first piece")
+	case a.Compression != b.Compression:
+		msg := "Compressor %q differs from %q"
+		return fmt.Errorf(msg, b.Compression, a.Compression)
+	}
+	return nil
+}
+
+// sameDeclarations returns an error when the points, coordinates, point data,
+// or cell data of the first piece of both headers are not declared equally.
+func sameDeclarations(a, b *Header) error {
+	pa, pb := a.Grid.Pieces[0], b.Grid.Pieces[0]
+
+	pairs := []struct {
+		name string
+		a, b *dataArray
+	}{
+		{"PointData", pa.PointData, pb.PointData},
+		{"CellData", pa.CellData, pb.CellData},
+		{"Points", pa.Points, pb.Points},
+		{"Coordinates", pa.Coordinates, pb.Coordinates},
+	}
+
+	for _, pair := range pairs {
+		da, db := declare(pair.a), declare(pair.b)
+		if da == nil && db == nil {
+			continue
+		}
+		if da == nil || db == nil || len(da.Data) != len(db.Data) {
+			return fmt.Errorf("Different number of %s arrays", pair.name)
+		}
+		for i := range da.Data {
+			if *da.Data[i] != *db.Data[i] {
+				msg := "%s array %q differs from %q"
+				return fmt.Errorf(msg, pair.name, db.Data[i].Name,
+					da.Data[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// setSources sets the Source of each piece from the prefix of the parallel
+// data set.
+func (p *Parallel) setSources() {
+	p.Grid.Pieces = make([]ppiece, len(p.pieces))
+	for i, h := range p.pieces {
+		p.Grid.Pieces[i].Source = p.pieceFilename(i)
+
+		switch h.Type {
+		case imageData, rectilinearGrid, structuredGrid:
+			ext := h.Grid.Extent
+			p.Grid.Pieces[i].Extent = &ext
+		}
+	}
+}
+
+// pieceFilename returns the file name of piece i.
+func (p *Parallel) pieceFilename(i int) string {
+	return fmt.Sprintf("%s_%d.%s", p.prefix, i, p.pieces[i].FileExtension())
+}
+
+// Len returns the number of pieces in the parallel data set.
+func (p *Parallel) Len() int {
+	return len(p.pieces)
+}
+
+// FileExtension returns the extension of the summary file.
+func (p *Parallel) FileExtension() string {
+	return "p" + p.pieces[0].FileExtension()
+}
+
+// Write writes the summary as encoded XML to the provided io.Writer. The
+// pieces are referred to as `piece_%d.ext` unless the names have been
+// updated by a call to Save.
+func (p *Parallel) Write(w io.Writer) error {
+	_, err := w.Write([]byte(xml.Header))
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(p)
+}
+
+// Save writes all pieces and the summary to file. The pieces are stored next
+// to the summary, named after the summary's base name followed by the piece
+// number, e.g. `out.pvtu` refers to `out_0.vtu`, `out_1.vtu`, etc. The
+// summary is synced to disk when any of the pieces is, see Sync().
+func (p *Parallel) Save(filename string) error {
+	if filepath.Ext(filename) == "" {
+		filename += "." + p.FileExtension()
+	}
+
+	dir := filepath.Dir(filename)
+	p.prefix = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	p.setSources()
+
+	sync := false
+	for i, h := range p.pieces {
+		path := filepath.Join(dir, p.pieceFilename(i))
+		if err := saveFile(path, h.sync, h.Write); err != nil {
+			return err
+		}
+		sync = sync || h.sync
+	}
+	return saveFile(filename, sync, p.Write)
+}
@@ -0,0 +1,139 @@
+package govtk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParallelUnstructured(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := NewParallel(newTetra(t, Raw()), newTetra(t, Raw()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FileExtension() != "pvtu" {
+		t.Errorf("Wrong extension: got %v, exp %v", p.FileExtension(), "pvtu")
+	}
+
+	if err := p.Save(filepath.Join(dir, "out")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"out.pvtu", "out_0.vtu", "out_1.vtu"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Missing file: %v", err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "out.pvtu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`type="PUnstructuredGrid"`,
+		`<PUnstructuredGrid GhostLevel="0">`,
+		`<PPointData><PDataArray type="Float64" Name="temperature"`,
+		`<PCellData><PDataArray type="Float64" Name="velocity" NumberOfComponents="3">`,
+		`<PPoints><PDataArray type="Float64" Name="Points" NumberOfComponents="3">`,
+		`<Piece Source="out_0.vtu"></Piece>`,
+		`<Piece Source="out_1.vtu"></Piece>`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("Missing %s in %s", s, b)
+		}
+	}
+}
+
+func TestParallelImage(t *testing.T) {
+	a, _ := Image(WholeExtent(0, 2, 0, 2, 0, 2), Spacing(1, 1, 1))
+	b, _ := Image(WholeExtent(2, 4, 0, 2, 0, 2), Spacing(1, 1, 1))
+	for _, h := range []*Header{a, b} {
+		if err := h.Add(PointData("f", make([]float64, 27))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := NewParallel(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := p.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`WholeExtent="0 4 0 2 0 2"`,
+		`<Piece Extent="0 2 0 2 0 2" Source="piece_0.vti"></Piece>`,
+		`<Piece Extent="2 4 0 2 0 2" Source="piece_1.vti"></Piece>`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Missing %s in %s", s, buf.String())
+		}
+	}
+
+	// pieces with different spacing cannot be combined
+	c, _ := Image(WholeExtent(0, 2, 0, 2, 0, 2), Spacing(2, 2, 2))
+	c.Add(PointData("f", make([]float64, 27)))
+	if _, err := NewParallel(a, c); err == nil {
+		t.Error("Pieces with different spacing should return error")
+	}
+}
+
+func TestParallelMismatch(t *testing.T) {
+	if _, err := NewParallel(); err == nil {
+		t.Error("Parallel without pieces should return error")
+	}
+
+	img, _ := Image(WholeExtent(0, 1, 0, 1, 0, 1))
+	img.Add(PointData("f", make([]float64, 8)))
+	if _, err := NewParallel(newTetra(t), img); err == nil {
+		t.Error("Pieces of different formats should return error")
+	}
+
+	other := newTetra(t)
+	other.Add(PointData("pressure", []float64{1, 2, 3, 4}))
+	if _, err := NewParallel(newTetra(t), other); err == nil {
+		t.Error("Pieces with different arrays should return error")
+	}
+
+	single, _ := Unstructured()
+	err := single.Add(
+		Points([]float32{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}),
+		Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Tetra}),
+		PointData("temperature", []float64{1, 2, 3, 4}),
+		CellData("velocity", []float64{1, 0, 0}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewParallel(newTetra(t), single); err == nil {
+		t.Error("Pieces with different point types should return error")
+	}
+
+	for _, opt := range []Option{Raw(), Compressed(), HeaderType64(),
+		FormatVersion(2, 2)} {
+		if _, err := NewParallel(newTetra(t), newTetra(t, opt)); err == nil {
+			t.Error("Pieces with different encodings should return error")
+		}
+	}
+
+	if _, err := NewParallel(newTetra(t, Legacy())); err == nil {
+		t.Error("Legacy pieces should return error")
+	}
+
+	multi := newTetra(t)
+	multi.Add(Piece())
+	if _, err := NewParallel(newTetra(t), multi); err == nil {
+		t.Error("Headers holding multiple pieces should return error")
+	}
+}
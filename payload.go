@@ -3,20 +3,28 @@ package govtk
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"math"
 )
 
 // Payload contains the data for a single dataarray in the vtk format.
 // The data is represented as a byte slice for the header and the body.
 //
-// For uncompressed payloads the header is a single int32.
-// For compressed payloads the header is a set of by four int32:
-// 	 number of blocks (currently always == 1)
-// 	 bytes current body
-// 	 bytes previous body (when num blocks == 1: equal to current block)
-// 	 bytes compressed block
+// For uncompressed payloads the header is a single value, for compressed
+// payloads the header is a set of values, see compressBlocks. The values are
+// stored as uint32, or as uint64 for header64:
+//
+//	number of blocks
+//	bytes of an uncompressed block
+//	bytes of the last uncompressed block (0 if it is a full block)
+//	bytes of each compressed block
 type payload struct {
 	head *bytes.Buffer
 	body *bytes.Buffer
+
+	// header64 is true when the header values are stored as uint64, i.e.
+	// header_type="UInt64", instead of as uint32.
+	header64 bool
 }
 
 // NewPayload returns a pointer to a payload with empty buffers.
@@ -25,43 +33,141 @@ func newPayload() *payload {
 }
 
 // NewPayloadFromData returns a pointer to payload constructed for the
-// data interface{}. The header is set after filling, no matter if the
-// write operation failed. It is up to the caller to verify err == nil.
-func newPayloadFromData(data interface{}) (*payload, error) {
+// data interface{}. The header is set after filling, using uint64 values for
+// header64. Any failure is returned as ErrEncoding.
+func newPayloadFromData(data interface{}, header64 bool) (*payload, error) {
 	p := newPayload()
+	p.header64 = header64
+
+	err := binary.Write(p.body, binary.LittleEndian, fixedSize(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEncoding, err)
+	}
+
+	if err := p.setHeader(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
 
+// fixedSize converts the platform dependent int and uint, and slices
+// thereof, towards int64 and uint64 respectively. All other data is returned
+// as is. This is required as binary encoding only accepts fixed size data.
+func fixedSize(data interface{}) interface{} {
 	switch v := data.(type) {
+	case int:
+		return int64(v)
+	case uint:
+		return uint64(v)
 	case []int:
-		for _, x := range v {
-			err := binary.Write(p.body, binary.LittleEndian, int32(x))
-			if err != nil {
-				return nil, err
-			}
+		res := make([]int64, len(v))
+		for i, x := range v {
+			res[i] = int64(x)
 		}
-	default:
-		err := binary.Write(p.body, binary.LittleEndian, data)
-		if err != nil {
-			return nil, err
+		return res
+	case []uint:
+		res := make([]uint64, len(v))
+		for i, x := range v {
+			res[i] = uint64(x)
 		}
+		return res
 	}
-
-	p.setHeader()
-	return p, nil
+	return data
 }
 
 // setHeader sets the header buffer with the data's length in bytes.
 func (p *payload) setHeader() error {
+	return p.setHeaderValues([]uint64{uint64(p.body.Len())})
+}
+
+// setHeaderValues sets the header buffer with the provided values. Depending
+// on header64 the values are written as uint32 or uint64.
+func (p *payload) setHeaderValues(vals []uint64) error {
 	p.head.Reset()
-	return binary.Write(p.head, binary.LittleEndian, int32(p.body.Len()))
+	for _, v := range vals {
+		if p.header64 {
+			if err := binary.Write(p.head, binary.LittleEndian, v); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if v > math.MaxUint32 {
+			return fmt.Errorf("%w: %d", errHeaderOverflow, v)
+		}
+		if err := binary.Write(p.head, binary.LittleEndian, uint32(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// headerValues returns the values stored in the header buffer. Depending on
+// header64 the values are read as uint32 or uint64.
+func (p *payload) headerValues() ([]uint64, error) {
+	size := 4
+	if p.header64 {
+		size = 8
+	}
+
+	b := p.head.Bytes()
+	if len(b)%size != 0 {
+		return nil, fmt.Errorf("Header of %d bytes is not a multiple of %d",
+			len(b), size)
+	}
+
+	vals := make([]uint64, len(b)/size)
+	for i := range vals {
+		if p.header64 {
+			vals[i] = binary.LittleEndian.Uint64(b[i*size:])
+		} else {
+			vals[i] = uint64(binary.LittleEndian.Uint32(b[i*size:]))
+		}
+	}
+	return vals, nil
+}
+
+// blockSizes returns the compressed size of each block as stored in the
+// header of a compressed payload.
+func (p *payload) blockSizes() ([]uint64, error) {
+	vals, err := p.headerValues()
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) < 3 || uint64(len(vals)) != 3+vals[0] {
+		return nil, fmt.Errorf("Invalid compression header: %v", vals)
+	}
+	return vals[3:], nil
+}
+
+// uncompressedSizes returns the uncompressed size of each block as stored
+// in the header of a compressed payload. All blocks are full, except for the
+// last block when the header defines a partial last block.
+func (p *payload) uncompressedSizes() ([]uint64, error) {
+	if _, err := p.blockSizes(); err != nil {
+		return nil, err
+	}
+
+	vals, _ := p.headerValues()
+	sizes := make([]uint64, vals[0])
+	for i := range sizes {
+		sizes[i] = vals[1]
+	}
+	if len(sizes) > 0 && vals[2] > 0 {
+		sizes[len(sizes)-1] = vals[2]
+	}
+	return sizes, nil
 }
 
 // compressed returns true if the payload has been compressed.
 func (p *payload) isCompressed() bool {
-	if p.head.Len() == 4 {
-		// a single int32 header implies no compression
-		return false
+	size := 4
+	if p.header64 {
+		size = 8
 	}
-	return true
+
+	// a single header value implies no compression
+	return p.head.Len() != size
 }
 
 // Reset resets both byte slices of the payload.
@@ -3,6 +3,9 @@ package govtk
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"io"
+	"math"
 	"testing"
 )
 
@@ -31,7 +34,7 @@ func TestNewPayloadFromData(t *testing.T) {
 	tmp := new(bytes.Buffer)
 	for _, data := range datas {
 
-		p, _ := newPayloadFromData(data)
+		p, _ := newPayloadFromData(data, false)
 		if p.head.Len() != 4 {
 			t.Errorf("Wrong header length: exp: %v, got: %v",
 				4, p.head.Len())
@@ -60,7 +63,7 @@ func TestNewPayloadFromData(t *testing.T) {
 }
 
 func TestPayloadFromInvalidData(t *testing.T) {
-	_, err := newPayloadFromData(string("-"))
+	_, err := newPayloadFromData(string("-"), false)
 	if err == nil {
 		t.Errorf("Payload should return not nil for faulty input")
 	}
@@ -74,4 +77,90 @@ func TestSetHeader(t *testing.T) {
 		t.Errorf("Int32 header not right length: %v", p.head.Len())
 	}
 
+	p.header64 = true
+	p.setHeader()
+	if p.head.Len() != 8 {
+		t.Errorf("Int64 header not right length: %v", p.head.Len())
+	}
+}
+
+func TestHeaderOverflow(t *testing.T) {
+	p := newPayload()
+	err := p.setHeaderValues([]uint64{1, math.MaxUint32 + 1})
+	if !errors.Is(err, errHeaderOverflow) || !errors.Is(err, ErrEncoding) {
+		t.Errorf("Expected header overflow, got: %v", err)
+	}
+
+	p.header64 = true
+	if err := p.setHeaderValues([]uint64{1, math.MaxUint32 + 1}); err != nil {
+		t.Errorf("UInt64 header should not overflow: %v", err)
+	}
+	vals, _ := p.headerValues()
+	if len(vals) != 2 || vals[1] != math.MaxUint32+1 {
+		t.Errorf("Wrong header values: %v", vals)
+	}
+}
+
+func TestHeaderValues(t *testing.T) {
+	p := newPayload()
+	binaryWrite(t, p.head, []uint32{2, 8, 4, 10, 12})
+	sizes, err := p.blockSizes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 2 || sizes[0] != 10 || sizes[1] != 12 {
+		t.Errorf("Wrong block sizes: %v", sizes)
+	}
+
+	p = newPayload()
+	p.header64 = true
+	binaryWrite(t, p.head, []uint64{1, 8, 8, 10})
+	sizes, err = p.blockSizes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 1 || sizes[0] != 10 {
+		t.Errorf("Wrong block sizes: %v", sizes)
+	}
+
+	// number of blocks does not match the header's length
+	p = newPayload()
+	binaryWrite(t, p.head, []uint32{3, 8, 4, 10})
+	if _, err := p.blockSizes(); err == nil {
+		t.Error("Invalid header should return error")
+	}
+}
+
+// binaryWrite writes the data as little endian towards the writer.
+func binaryWrite(t *testing.T, w io.Writer, data interface{}) {
+	if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure platform dependent integers are written as 64 bit values.
+func TestPayloadFromInts(t *testing.T) {
+	cases := []struct {
+		data, exp interface{}
+	}{
+		{int(-1), int64(-1)},
+		{uint(1), uint64(1)},
+		{[]int{-1, 0, 1 << 40}, []int64{-1, 0, 1 << 40}},
+		{[]uint{0, 1 << 40}, []uint64{0, 1 << 40}},
+	}
+
+	tmp := new(bytes.Buffer)
+	for _, c := range cases {
+		p, err := newPayloadFromData(c.data, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tmp.Reset()
+		binaryWrite(t, tmp, c.exp)
+		if !bytes.Equal(p.body.Bytes(), tmp.Bytes()) {
+			t.Errorf("Wrong body content for %T: exp %#v, got %#v",
+				c.data, tmp.Bytes(), p.body.Bytes())
+		}
+	}
 }
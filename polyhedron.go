@@ -0,0 +1,112 @@
+package govtk
+
+import "fmt"
+
+// PolyhedronCells sets the element connectivity of an unstructured grid
+// containing polyhedral cells. The conn, offset, and labels follow Cells,
+// where the connectivity of a polyhedron lists its unique points. The faces
+// hold for each cell its faces, given by their point ids, or nil for cells
+// other than polyhedra, e.g.
+//
+//	faces[i] = [][]int{{0, 1, 2}, {0, 1, 3}, {1, 2, 3}, {0, 2, 3}}
+//
+// The faces are written in the layout of VTK 9 (face_connectivity,
+// face_offsets, polyhedron_to_faces, polyhedron_offsets) as well as the
+// layout of prior versions (faces, faceoffsets), such that the file is read
+// by either version. For FormatVersion(2, 2) only the former is written.
+func PolyhedronCells(conn, offset, labels []int, faces [][][]int) Option {
+	return func(h *Header) error {
+		if len(faces) != len(labels) {
+			msg := "Number of cell faces %d does not match number of labels %d"
+			return fmt.Errorf(msg, len(faces), len(labels))
+		}
+
+		types, err := h.mapLabelToType(labels)
+		if err != nil {
+			return err
+		}
+		for i, t := range types {
+			if (t == Polyhedron) != (faces[i] != nil) {
+				msg := "Cell %d of type %d requires faces only for polyhedra"
+				return fmt.Errorf(msg, i, t)
+			}
+		}
+
+		if err := Cells(conn, offset, labels)(h); err != nil {
+			return err
+		}
+		lp, err := h.lastPiece()
+		if err != nil {
+			return err
+		}
+		return newFaceArrays(faces).add(lp.Cells)
+	}
+}
+
+// faceArrays holds the faces of the cells in the layouts of the VTK formats.
+// The legacy layout stores for each polyhedron its number of faces, followed
+// by the number of points and point ids of each face. Its offsets refer to
+// the end of the polyhedron's faces or hold -1 for other cells. The layout of
+// VTK 9 stores the faces once, which are referred to by the polyhedra, using
+// offsets with a leading zero.
+type faceArrays struct {
+	faces, faceOffsets []int
+
+	faceConn, faceConnOffsets []int
+	polyFaces, polyOffsets    []int
+}
+
+// newFaceArrays converts the faces of each cell towards the face arrays.
+func newFaceArrays(faces [][][]int) *faceArrays {
+	fa := &faceArrays{
+		faceOffsets:     make([]int, len(faces)),
+		faceConnOffsets: []int{0},
+		polyOffsets:     []int{0},
+	}
+
+	for i, cell := range faces {
+		fa.faceOffsets[i] = -1
+		if cell != nil {
+			fa.faces = append(fa.faces, len(cell))
+			for _, face := range cell {
+				fa.faces = append(fa.faces, len(face))
+				fa.faces = append(fa.faces, face...)
+
+				id := len(fa.faceConnOffsets) - 1
+				fa.polyFaces = append(fa.polyFaces, id)
+				fa.faceConn = append(fa.faceConn, face...)
+				fa.faceConnOffsets = append(fa.faceConnOffsets, len(fa.faceConn))
+			}
+			fa.faceOffsets[i] = len(fa.faces)
+		}
+		fa.polyOffsets = append(fa.polyOffsets, len(fa.polyFaces))
+	}
+	return fa
+}
+
+// add adds the face arrays to the cells. Format version 1 holds both
+// layouts, while version 2 only holds the layout of VTK 9.
+func (fa *faceArrays) add(cells *dataArray) error {
+	type array struct {
+		name   string
+		values []int
+	}
+
+	var arrays []array
+	if !cells.leadingZero {
+		arrays = append(arrays, array{"faces", fa.faces},
+			array{"faceoffsets", fa.faceOffsets})
+	}
+	arrays = append(arrays,
+		array{"face_connectivity", fa.faceConn},
+		array{"face_offsets", fa.faceConnOffsets},
+		array{"polyhedron_to_faces", fa.polyFaces},
+		array{"polyhedron_offsets", fa.polyOffsets})
+
+	for _, arr := range arrays {
+		if err := cells.addIDs(arr.name, arr.values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
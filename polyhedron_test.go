@@ -0,0 +1,88 @@
+package govtk
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newPolyhedra returns a tetrahedron followed by the same tetrahedron given
+// as polyhedron.
+func newPolyhedra(t *testing.T, opts ...Option) *Header {
+	vtu, err := Unstructured(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coords := []float64{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}
+	conn := []int{0, 1, 2, 3, 0, 1, 2, 3}
+	offset := []int{0, 4, 8}
+	labels := []int{Tetra, Polyhedron}
+	faces := [][][]int{nil, {{0, 2, 1}, {0, 1, 3}, {1, 2, 3}, {0, 3, 2}}}
+
+	err = vtu.Add(Points(coords), PolyhedronCells(conn, offset, labels, faces))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vtu
+}
+
+func TestPolyhedronCells(t *testing.T) {
+	vtu := newPolyhedra(t, Raw())
+	if err := vtu.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// the legacy format does not define the faces of polyhedra
+	vtk := newPolyhedra(t, Legacy())
+	if err := vtk.Write(new(bytes.Buffer)); err == nil {
+		t.Error("Legacy with polyhedron cells should return error")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := vtu.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string][]int{
+		"faces":               {4, 3, 0, 2, 1, 3, 0, 1, 3, 3, 1, 2, 3, 3, 0, 3, 2},
+		"faceoffsets":         {-1, 17},
+		"face_connectivity":   {0, 2, 1, 0, 1, 3, 1, 2, 3, 0, 3, 2},
+		"face_offsets":        {0, 3, 6, 9, 12},
+		"polyhedron_to_faces": {0, 1, 2, 3},
+		"polyhedron_offsets":  {0, 0, 4},
+	}
+	for name, values := range exp {
+		got, err := r.Grid.Pieces[0].Cells.values(name)
+		if err != nil {
+			t.Errorf("Missing array %s: %v", name, err)
+			continue
+		}
+		if ints, _ := toInts(got); !reflect.DeepEqual(ints, values) {
+			t.Errorf("Wrong %s: got %v, exp %v", name, ints, values)
+		}
+	}
+
+	// faces are required for polyhedra only
+	vtu, _ = Unstructured()
+	err = vtu.Add(PolyhedronCells([]int{0, 1, 2, 3}, []int{0, 4},
+		[]int{Tetra}, [][][]int{{{0, 1, 2}}}))
+	if err == nil {
+		t.Errorf("Expected error for faces of a tetrahedron")
+	}
+	if len(vtu.Grid.Pieces) > 0 && vtu.Grid.Pieces[0].Cells != nil {
+		t.Errorf("Cells set despite error")
+	}
+
+	// polyhedra without faces are invalid
+	vtu, _ = Unstructured(Points([]float64{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}),
+		Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Polyhedron}))
+	if err := vtu.Validate(); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("Expected invalid mesh, got: %v", err)
+	}
+}
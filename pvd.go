@@ -1,12 +1,12 @@
 package govtk
 
 import (
-	"bufio"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // PVD represent a ParaViewData (PVD) format. The PVD file contains a single
@@ -34,6 +34,9 @@ type PVD struct {
 	// collection. By default only relative paths with respect to `pvd.dir`
 	// are written.
 	fullpath bool
+
+	// On true syncs the PVD and its data files to disk, see SyncPVD().
+	sync bool
 }
 
 // Initialise a PVD collection with options.
@@ -58,6 +61,86 @@ func NewPVD(opts ...PVDOption) (*PVD, error) {
 	return pvd, nil
 }
 
+// OpenPVD reads an existing PVD collection, e.g. to continue writing the
+// collection after restarting a simulation. The directory is set to the
+// directory of the PVD file and the file name format is inferred from the
+// last data set, such that subsequent calls to Add continue the numbering
+// (and default time steps) of the collection. Additional options are
+// applied after restoring the collection.
+func OpenPVD(path string, opts ...PVDOption) (*PVD, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pvd, err := NewPVD(Directory(filepath.Dir(path)))
+	if err != nil {
+		return nil, err
+	}
+	if err := xml.NewDecoder(f).Decode(pvd); err != nil {
+		return nil, err
+	}
+
+	if n := pvd.Len(); n > 0 {
+		last := pvd.Collection[n-1].Filename
+		if filepath.IsAbs(last) {
+			pvd.fullpath = true
+			pvd.dir = filepath.Dir(last)
+			last = filepath.Base(last)
+		}
+		if format, ok := inferFileFormat(last); ok {
+			pvd.filenameFormat = format
+		}
+	}
+
+	for _, opt := range opts {
+		if err := opt(pvd); err != nil {
+			return nil, err
+		}
+	}
+	return pvd, nil
+}
+
+// inferFileFormat returns the formatting string that generated the filename,
+// assuming the file number directly precedes the extension, e.g.
+// `file_007.vtu` results in `file_%03d.%s`. Returns false when the filename
+// does not end with a number.
+func inferFileFormat(filename string) (string, bool) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+
+	i := len(base)
+	for i > 0 && base[i-1] >= '0' && base[i-1] <= '9' {
+		i--
+	}
+	digits := base[i:]
+	if ext == "" || digits == "" {
+		return "", false
+	}
+
+	prefix := strings.Replace(base[:i], "%", "%%", -1)
+	if len(digits) > 1 && digits[0] == '0' {
+		return fmt.Sprintf("%s%%0%dd.%%s", prefix, len(digits)), true
+	}
+	return prefix + "%d.%s", true
+}
+
+// Truncate removes all data sets with a time step beyond the provided time.
+// When restarting a simulation from an earlier state, this removes the data
+// sets that will be written again. Subsequent calls to Add continue the
+// numbering after the remaining data sets.
+func (pvd *PVD) Truncate(time float64) {
+	n := 0
+	for _, d := range pvd.Collection {
+		if d.TimeStep <= time {
+			pvd.Collection[n] = d
+			n++
+		}
+	}
+	pvd.Collection = pvd.Collection[:n]
+}
+
 // Options for the PVD collection.
 type PVDOption func(pvd *PVD) error
 
@@ -85,17 +168,22 @@ func RelativeFilenames() PVDOption {
 // Directory sets the directory to store the files of the PVD collection.
 func Directory(dir string) PVDOption {
 	return func(pvd *PVD) error {
-		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			err := os.Mkdir(dir, os.ModePerm)
-			if err != nil {
-				return err
-			}
+		if err := ensureDir(dir); err != nil {
+			return err
 		}
 		pvd.dir = dir
 		return nil
 	}
 }
 
+// ensureDir creates the directory when it does not exist.
+func ensureDir(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.Mkdir(dir, os.ModePerm)
+	}
+	return nil
+}
+
 // SetFileFormat sets the formatting string for the automatic file naming. This
 // requires a single %d for the file number and a %s for the extension.
 func SetFileFormat(format string) PVDOption {
@@ -234,12 +322,7 @@ func (pvd *PVD) Add(h *Header, opts ...DSOption) error {
 		path = filepath.Join(pvd.Dir(), filename)
 	}
 
-	f, err := os.Create(path)
-	defer f.Close()
-	if err != nil {
-		return err
-	}
-	return h.Write(bufio.NewWriter(f))
+	return saveFile(path, pvd.sync || h.sync, h.Write)
 }
 
 // Write writes the PVD as encoded XML to the provided io.Writer.
@@ -251,16 +334,10 @@ func (pvd *PVD) Write(w io.Writer) error {
 	return xml.NewEncoder(w).Encode(pvd)
 }
 
-// Save opens a file and writes the XML to file.
+// Save writes the XML to file, see saveFile.
 func (pvd *PVD) Save(filename string) error {
 	if filepath.Ext(filename) == "" {
 		filename += ".pvd"
 	}
-
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	return pvd.Write(bufio.NewWriter(f))
+	return saveFile(filename, pvd.sync, pvd.Write)
 }
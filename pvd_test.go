@@ -1,6 +1,7 @@
 package govtk
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -222,3 +223,97 @@ func TestPVD_write(t *testing.T) {
 	}
 }
 */
+
+// Ensure a saved collection is restored and continues its numbering.
+func TestOpenPVD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	im, err := Image(WholeExtent(0, 2, 0, 2, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pvd, err := NewPVD(Directory(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := pvd.Add(im, Time(0.5*float64(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "sim.pvd")
+	if err := pvd.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := OpenPVD(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 3 {
+		t.Fatalf("Wrong length: got %v, exp %v", restored.Len(), 3)
+	}
+	if restored.Dir() != dir {
+		t.Errorf("Wrong directory: got %v, exp %v", restored.Dir(), dir)
+	}
+	if restored.Collection[2].TimeStep != 1.0 {
+		t.Errorf("Wrong time step: got %v", restored.Collection[2].TimeStep)
+	}
+
+	// restart from t = 0.5, rewriting the last file
+	restored.Truncate(0.5)
+	if err := restored.Add(im, Time(0.75)); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Add(im); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"file_000.vti", "file_001.vti", "file_002.vti", "file_003.vti"}
+	for i, n := range names {
+		if restored.Collection[i].Filename != n {
+			t.Errorf("Wrong filename: got %v, exp %s",
+				restored.Collection[i].Filename, n)
+		}
+		if _, err := os.Stat(filepath.Join(dir, n)); err != nil {
+			t.Errorf("Missing file: %v", err)
+		}
+	}
+	if restored.Collection[2].TimeStep != 0.75 {
+		t.Errorf("Wrong time step: got %v", restored.Collection[2].TimeStep)
+	}
+	if restored.Collection[3].TimeStep != 3.0 {
+		t.Errorf("Wrong default time step: got %v", restored.Collection[3].TimeStep)
+	}
+
+	if _, err := OpenPVD(filepath.Join(dir, "missing.pvd")); err == nil {
+		t.Error("Opening missing file should return error")
+	}
+}
+
+func TestInferFileFormat(t *testing.T) {
+	cases := []struct {
+		name, format string
+		ok           bool
+	}{
+		{"file_000.vtu", "file_%03d.%s", true},
+		{"file_12.vti", "file_%d.%s", true},
+		{"run%1_0042.vts", "run%%1_%04d.%s", true},
+		{"data/step7.vtu", "data/step%d.%s", true},
+		{"mesh.vtu", "", false},
+		{"file_001", "", false},
+	}
+	for _, c := range cases {
+		format, ok := inferFileFormat(c.name)
+		if ok != c.ok || format != c.format {
+			t.Errorf("Wrong format for %s: got %q (%v), exp %q (%v)",
+				c.name, format, ok, c.format, c.ok)
+		}
+	}
+}
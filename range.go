@@ -0,0 +1,117 @@
+package govtk
+
+import (
+	"math"
+	"reflect"
+)
+
+// DataRange computes the range of each array added afterwards, which is
+// written as RangeMin and RangeMax of the array, similar to VTK's writers.
+// For arrays with multiple components, the range of the magnitude of the
+// tuples is written. Parallel data sets declare the range over all pieces.
+func DataRange() Option {
+	return func(h *Header) error {
+		h.ranges = true
+		return nil
+	}
+}
+
+// valueRange returns the minimum and maximum of the values, or of the
+// magnitude of the tuples of multiple components. NaN values are ignored.
+// Both are nil when the data holds no (valid) values.
+func valueRange(data interface{}, components int) (*float64, *float64) {
+	at, n := floatValues(data)
+	if components < 1 {
+		components = 1
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := 0; i+components <= n; i += components {
+		v := at(i)
+		if components > 1 {
+			sum := 0.0
+			for j := 0; j < components; j++ {
+				x := at(i + j)
+				sum += x * x
+			}
+			v = math.Sqrt(sum)
+		}
+
+		if math.IsNaN(v) {
+			continue
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+
+	if lo > hi {
+		return nil, nil
+	}
+	return &lo, &hi
+}
+
+// floatValues returns a function accessing the data as float64 values and
+// the number of values. Single values are treated as a slice of length one.
+// Data that is not numeric holds no values.
+func floatValues(data interface{}) (func(i int) float64, int) {
+	switch v := data.(type) {
+	case []float64:
+		return func(i int) float64 { return v[i] }, len(v)
+	case []float32:
+		return func(i int) float64 { return float64(v[i]) }, len(v)
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		v = reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+		v.Index(0).Set(reflect.ValueOf(data))
+	}
+
+	var at func(i int) float64
+	switch v.Type().Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		at = func(i int) float64 { return float64(v.Index(i).Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		at = func(i int) float64 { return float64(v.Index(i).Uint()) }
+	case reflect.Float32, reflect.Float64:
+		at = func(i int) float64 { return v.Index(i).Float() }
+	default:
+		return nil, 0
+	}
+	return at, v.Len()
+}
+
+// declareRanges sets the range of each declared array to the union of its
+// ranges over all pieces. The range is only declared when all pieces hold
+// the range of the array.
+func declareRanges(pda *pdataArray, headers []*Header,
+	data func(p *partition) *dataArray) {
+	if pda == nil {
+		return
+	}
+
+	for _, decl := range pda.Data {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		complete := true
+		for _, h := range headers {
+			for i := range h.Grid.Pieces {
+				da := data(&h.Grid.Pieces[i])
+				if da == nil {
+					complete = false
+					continue
+				}
+				arr := da.lookup(decl.Name)
+				if arr == nil || arr.RangeMin == nil || arr.RangeMax == nil {
+					complete = false
+					continue
+				}
+				lo, hi = math.Min(lo, *arr.RangeMin), math.Max(hi, *arr.RangeMax)
+			}
+		}
+
+		if complete && lo <= hi {
+			decl.RangeMin, decl.RangeMax = &lo, &hi
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package govtk
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValueRange(t *testing.T) {
+	tests := []struct {
+		data       interface{}
+		components int
+		lo, hi     float64
+		ok         bool
+	}{
+		{[]float64{3, -1, 2}, 1, -1, 3, true},
+		{[]float32{3, -1, 2}, 0, -1, 3, true},
+		{[]int32{5, 7}, 1, 5, 7, true},
+		{[]uint8{5, 7}, 1, 5, 7, true},
+		{[]float64{3, 4, 0, 0, 1, 0}, 2, 0, 5, true},
+		{[]float64{math.NaN(), 2}, 1, 2, 2, true},
+		{int64(4), 1, 4, 4, true},
+		{[]float64{}, 1, 0, 0, false},
+		{[]float64{math.NaN()}, 1, 0, 0, false},
+		{true, 1, 0, 0, false},
+	}
+	for _, test := range tests {
+		lo, hi := valueRange(test.data, test.components)
+		if !test.ok {
+			if lo != nil || hi != nil {
+				t.Errorf("Expected no range for %v, got %v %v", test.data,
+					*lo, *hi)
+			}
+			continue
+		}
+		if lo == nil || hi == nil || *lo != test.lo || *hi != test.hi {
+			t.Errorf("Wrong range for %v: got %v %v, exp %v %v", test.data,
+				lo, hi, test.lo, test.hi)
+		}
+	}
+}
+
+func TestDataRange(t *testing.T) {
+	vtu := newTetra(t, DataRange())
+	buf := new(bytes.Buffer)
+	if err := vtu.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := `Name="temperature" format="binary" NumberOfComponents="1" ` +
+		`RangeMin="1" RangeMax="4"`
+	if !strings.Contains(buf.String(), exp) {
+		t.Errorf("Missing %s in output", exp)
+	}
+
+	// the range is written again after reading
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arr := r.Grid.Pieces[0].PointData.lookup("temperature")
+	if arr.RangeMin == nil || *arr.RangeMin != 1 {
+		t.Errorf("Range not restored")
+	}
+
+	// the parallel data set declares the range over all pieces
+	other := newTetra(t, DataRange())
+	err = other.Add(PointData("pressure", []float64{-2, 0, 0, 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vtu.Add(PointData("pressure", []float64{0, 0, 0, 8})); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewParallel(vtu, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := p.Grid.PPointData.Data[1]
+	if decl.RangeMin == nil || *decl.RangeMin != -2 || *decl.RangeMax != 8 {
+		t.Errorf("Wrong parallel range: %v %v", decl.RangeMin, decl.RangeMax)
+	}
+
+	// the range applies to arrays added afterwards, also when their point
+	// data was created before DataRange()
+	vti, _ := Image(WholeExtent(0, 1, 0, 1, 0, 1))
+	a := make([]float64, 8)
+	err = vti.Add(PointData("a", a), DataRange(), PointData("b", a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd := vti.Grid.Pieces[0].PointData
+	if pd.lookup("a").RangeMin != nil || pd.lookup("b").RangeMin == nil {
+		t.Errorf("Range should only apply to arrays added after DataRange")
+	}
+
+	// no range is declared when any piece lacks the range
+	p, err = NewParallel(newTetra(t, DataRange()), newTetra(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decl := p.Grid.PPointData.Data[0]; decl.RangeMin != nil {
+		t.Errorf("Unexpected parallel range: %v", *decl.RangeMin)
+	}
+}
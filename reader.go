@@ -0,0 +1,889 @@
+package govtk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xmlFile mirrors the VTK XML file structure for decoding. The grid element
+// is named after the file type, hence it is matched by any element that is
+// not the appended data.
+type xmlFile struct {
+	Type       string    `xml:"type,attr"`
+	Version    float64   `xml:"version,attr"`
+	ByteOrder  string    `xml:"byte_order,attr"`
+	HeaderType string    `xml:"header_type,attr"`
+	Compressor string    `xml:"compressor,attr"`
+	Appended   *xmlArray `xml:"AppendedData"`
+	Grid       xmlGrid   `xml:",any"`
+}
+
+// format returns the format of the first array in the file, or an empty
+// string when the file does not contain any arrays.
+func (f *xmlFile) format() string {
+	sections := []*xmlSection{f.Grid.FieldData}
+	for _, p := range f.Grid.Pieces {
+		sections = append(sections, p.Points, p.Cells, p.Verts, p.Lines,
+			p.Strips, p.Polys, p.Coordinates, p.PointData, p.CellData)
+	}
+	for _, s := range sections {
+		if s != nil && len(s.Arrays) > 0 {
+			return s.Arrays[0].Format
+		}
+	}
+	return ""
+}
+
+// xmlGrid mirrors Grid for decoding.
+type xmlGrid struct {
+	XMLName     xml.Name
+	WholeExtent string      `xml:"WholeExtent,attr"`
+	Origin      string      `xml:"Origin,attr"`
+	Spacing     string      `xml:"Spacing,attr"`
+	FieldData   *xmlSection `xml:"FieldData"`
+	Pieces      []xmlPiece  `xml:"Piece"`
+}
+
+// xmlPiece mirrors partition for decoding.
+type xmlPiece struct {
+	Extent         string      `xml:"Extent,attr"`
+	NumberOfPoints int         `xml:"NumberOfPoints,attr"`
+	NumberOfCells  int         `xml:"NumberOfCells,attr"`
+	NumberOfVerts  int         `xml:"NumberOfVerts,attr"`
+	NumberOfLines  int         `xml:"NumberOfLines,attr"`
+	NumberOfStrips int         `xml:"NumberOfStrips,attr"`
+	NumberOfPolys  int         `xml:"NumberOfPolys,attr"`
+	Points         *xmlSection `xml:"Points"`
+	Cells          *xmlSection `xml:"Cells"`
+	Verts          *xmlSection `xml:"Verts"`
+	Lines          *xmlSection `xml:"Lines"`
+	Strips         *xmlSection `xml:"Strips"`
+	Polys          *xmlSection `xml:"Polys"`
+	Coordinates    *xmlSection `xml:"Coordinates"`
+	PointData      *xmlSection `xml:"PointData"`
+	CellData       *xmlSection `xml:"CellData"`
+}
+
+// xmlSection mirrors dataArray for decoding.
+type xmlSection struct {
+	attributes
+	Arrays []*xmlArray `xml:"DataArray"`
+}
+
+// xmlArray mirrors darray for decoding. Any inline data is captured as
+// character data.
+type xmlArray struct {
+	Type               string `xml:"type,attr"`
+	Name               string `xml:"Name,attr"`
+	Format             string `xml:"format,attr"`
+	NumberOfComponents int    `xml:"NumberOfComponents,attr"`
+	NumberOfTuples     int    `xml:"NumberOfTuples,attr"`
+	Encoding           string `xml:"encoding,attr"`
+	Offset             *int   `xml:"offset,attr"`
+	Data               string `xml:",chardata"`
+
+	// remaining attributes and information keys, see restoreArray
+	Attrs []xml.Attr `xml:",any,attr"`
+	Info  []infoKey  `xml:"InformationKey"`
+}
+
+// Open reads the VTK XML file at path, see Read.
+func Open(path string) (*Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read decodes a VTK XML image, rectilinear, structured, unstructured, or
+// poly data file into a Header. The arrays can be stored inline as ascii or
+// base64, or appended as base64 or raw binary data, either with or without
+// zlib compression.
+//
+// The values of the arrays are available as typed slices through PointArray,
+// CellArray, FieldArray, etc. The header is setup with the file's appended
+// data and compression, such that it can be modified and written again. The
+// arrays keep their data as encoded in the file, such that these are written
+// without encoding them again. Only arrays that differ from the header's
+// format, e.g. inline arrays of appended files, are encoded again, where
+// inline arrays are written as base64.
+func Read(r io.Reader) (*Header, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Raw appended data breaks the XML, therefore the appended data is
+	// cut out before decoding the XML and decoded separately.
+	data, appended, err := splitAppended(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var file xmlFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	d, err := newXMLDecoder(&file, appended)
+	if err != nil {
+		return nil, err
+	}
+	return d.header()
+}
+
+// splitAppended returns the XML without the content of the AppendedData
+// element, and the content of the appended data following the leading
+// underscore. When no appended data is present, the data is returned as is.
+func splitAppended(data []byte) ([]byte, []byte, error) {
+	start := bytes.Index(data, []byte("<AppendedData"))
+	if start < 0 {
+		return data, nil, nil
+	}
+
+	marker := bytes.IndexByte(data[start:], '_')
+	end := bytes.LastIndex(data, []byte("</AppendedData>"))
+	if marker < 0 || end < start+marker {
+		return nil, nil, fmt.Errorf("Malformed AppendedData element")
+	}
+	marker += start
+
+	xmlData := make([]byte, 0, marker+len(data)-end)
+	xmlData = append(xmlData, data[:marker]...)
+	xmlData = append(xmlData, data[end:]...)
+	return xmlData, data[marker+1 : end], nil
+}
+
+// xmlDecoder decodes the arrays of a VTK XML file.
+type xmlDecoder struct {
+	file *xmlFile
+
+	// appended data following the underscore and its encoding
+	appended []byte
+	encoding string
+
+	// sorted offsets of all appended arrays, to find the end of each
+	// base64 encoded array in the appended data
+	offsets []int
+
+	header64   bool
+	compressed bool
+	compressor compressor
+}
+
+// newXMLDecoder validates the file's attributes and collects the offsets of
+// the appended arrays.
+func newXMLDecoder(file *xmlFile, appended []byte) (*xmlDecoder, error) {
+	d := &xmlDecoder{file: file, appended: appended}
+
+	switch file.Type {
+	case imageData, rectilinearGrid, structuredGrid, unstructuredGrid,
+		polyData:
+	default:
+		return nil, fmt.Errorf("File type '%s' not supported", file.Type)
+	}
+
+	if file.ByteOrder != "" && file.ByteOrder != "LittleEndian" {
+		return nil, fmt.Errorf("Byte order %s not supported", file.ByteOrder)
+	}
+
+	switch file.HeaderType {
+	case "", "UInt32":
+	case "UInt64":
+		d.header64 = true
+	default:
+		return nil, fmt.Errorf("Header type %s not supported", file.HeaderType)
+	}
+
+	d.compressed = file.Compressor != ""
+	switch file.Compressor {
+	case "":
+		d.compressor = noCompression{}
+	case zlibCompressor:
+		d.compressor = zlibCompression{level: DefaultCompression}
+	case lz4Compressor:
+		d.compressor = lz4Compression{level: DefaultCompression}
+	case lzmaCompressor:
+		d.compressor = lzmaCompression{}
+	default:
+		return nil, fmt.Errorf("Compressor %s not supported", file.Compressor)
+	}
+
+	if file.Appended != nil {
+		d.encoding = file.Appended.Encoding
+		for _, a := range d.arrays() {
+			if a.Offset != nil {
+				d.offsets = append(d.offsets, *a.Offset)
+			}
+		}
+		sort.Ints(d.offsets)
+	}
+	return d, nil
+}
+
+// arrays returns all arrays present in the file.
+func (d *xmlDecoder) arrays() []*xmlArray {
+	var res []*xmlArray
+	add := func(s *xmlSection) {
+		if s != nil {
+			res = append(res, s.Arrays...)
+		}
+	}
+
+	add(d.file.Grid.FieldData)
+	for _, p := range d.file.Grid.Pieces {
+		for _, s := range []*xmlSection{p.Points, p.Cells, p.Verts,
+			p.Lines, p.Strips, p.Polys, p.Coordinates, p.PointData,
+			p.CellData} {
+			add(s)
+		}
+	}
+	return res
+}
+
+// ranges returns true when any array of the file holds its range, see
+// DataRange.
+func (d *xmlDecoder) ranges() bool {
+	for _, a := range d.arrays() {
+		for _, attr := range a.Attrs {
+			if attr.Name.Local == "RangeMin" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// header constructs the Header from the decoded file. The header's format
+// and compression follow the file, the arrays are inserted as encoded in
+// the file, see section.
+func (d *xmlDecoder) header() (*Header, error) {
+	f := d.file
+
+	opts := []Option{}
+	switch {
+	case f.Appended != nil && d.encoding == encodingRaw:
+		opts = append(opts, Raw())
+	case f.Appended != nil:
+		opts = append(opts, Appended())
+	case f.format() == formatAscii:
+		opts = append(opts, Ascii())
+	}
+	if f.Compressor != "" {
+		opts = append(opts, CompressedWith(Algorithm(f.Compressor)))
+	}
+	if d.header64 {
+		opts = append(opts, HeaderType64())
+	}
+	if d.ranges() {
+		opts = append(opts, DataRange())
+	}
+
+	h, err := newHeader(f.Type, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if f.Version != 0 {
+		h.Version = f.Version
+	}
+	h.Grid.Origin = f.Grid.Origin
+	h.Grid.Spacing = f.Grid.Spacing
+
+	if f.Grid.WholeExtent != "" {
+		if h.Grid.Extent, err = parseBounds(f.Grid.WholeExtent); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Grid.FieldData != nil {
+		h.Grid.Data = h.NewFieldArray()
+		if err := d.section(h.Grid.Data, f.Grid.FieldData); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, xp := range f.Grid.Pieces {
+		p := partition{
+			poly:           f.Type == polyData,
+			NumberOfPoints: xp.NumberOfPoints,
+			NumberOfCells:  xp.NumberOfCells,
+			NumberOfVerts:  xp.NumberOfVerts,
+			NumberOfLines:  xp.NumberOfLines,
+			NumberOfStrips: xp.NumberOfStrips,
+			NumberOfPolys:  xp.NumberOfPolys,
+		}
+
+		if xp.Extent != "" {
+			if p.Extent, err = parseBounds(xp.Extent); err != nil {
+				return nil, err
+			}
+		}
+		// older versions wrote empty extents for all formats
+		if p.Extent != (bounds{}) {
+			p.NumberOfPoints = p.Extent.numPoints()
+			p.NumberOfCells = p.Extent.numCells()
+		}
+		if f.Type == polyData {
+			p.NumberOfCells = p.NumberOfVerts + p.NumberOfLines +
+				p.NumberOfStrips + p.NumberOfPolys
+		}
+
+		sections := []struct {
+			dst **dataArray
+			src *xmlSection
+		}{
+			{&p.Points, xp.Points},
+			{&p.Cells, xp.Cells},
+			{&p.Verts, xp.Verts},
+			{&p.Lines, xp.Lines},
+			{&p.Strips, xp.Strips},
+			{&p.Polys, xp.Polys},
+			{&p.Coordinates, xp.Coordinates},
+			{&p.PointData, xp.PointData},
+			{&p.CellData, xp.CellData},
+		}
+		for _, s := range sections {
+			if s.src == nil {
+				continue
+			}
+			*s.dst = h.NewArray()
+			(*s.dst).attributes = s.src.attributes
+			if err := d.section(*s.dst, s.src); err != nil {
+				return nil, err
+			}
+		}
+
+		h.Grid.Pieces = append(h.Grid.Pieces, p)
+	}
+	return h, nil
+}
+
+// section adds all arrays of the section to the dataArray. Arrays that match
+// the format of the dataArray are added as encoded in the file, any other
+// array is decoded and encoded again.
+func (d *xmlDecoder) section(da *dataArray, s *xmlSection) error {
+	for _, a := range s.Arrays {
+		n := a.NumberOfComponents
+		if da.fieldData {
+			n = a.NumberOfTuples
+		}
+		if n == 0 {
+			n = 1
+		}
+
+		if a.Format == da.format() {
+			encoded, err := d.encoded(a)
+			if err != nil {
+				return fmt.Errorf("Array '%s': %v", a.Name, err)
+			}
+			err = da.addEncoded(a.Name, a.Type, n, encoded, restoreArray(a)...)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		values, err := d.decode(a)
+		if err != nil {
+			return fmt.Errorf("Array '%s': %v", a.Name, err)
+		}
+		if err := da.add(a.Name, n, values, restoreArray(a)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encoded returns the data of the array as encoded in the file.
+func (d *xmlDecoder) encoded(a *xmlArray) ([]byte, error) {
+	switch a.Format {
+	case formatAscii, formatBinary:
+		return []byte(a.Data), nil
+	case formatAppended:
+		if a.Offset == nil || *a.Offset < 0 || *a.Offset > len(d.appended) {
+			return nil, fmt.Errorf("Invalid offset for appended data")
+		}
+		if d.encoding == encodingRaw {
+			data := d.appended[*a.Offset:]
+			nh, size, err := d.rawSize(data)
+			if err != nil {
+				return nil, err
+			}
+			return data[:nh*d.headerSize()+size], nil
+		}
+		data := d.appended[*a.Offset:d.end(*a.Offset)]
+		return bytes.TrimRight(data, " \t\r\n"), nil
+	}
+	return nil, fmt.Errorf("Unknown format '%s'", a.Format)
+}
+
+// decode returns the values of the array as a typed slice.
+func (d *xmlDecoder) decode(a *xmlArray) (interface{}, error) {
+	switch a.Format {
+	case formatAscii:
+		return parseAscii(a.Type, a.Data)
+	case formatBinary:
+		return d.decodeBase64(a.Type, []byte(a.Data))
+	case formatAppended:
+		if a.Offset == nil || *a.Offset < 0 || *a.Offset > len(d.appended) {
+			return nil, fmt.Errorf("Invalid offset for appended data")
+		}
+		if d.encoding == encodingRaw {
+			return d.decodeRaw(a.Type, d.appended[*a.Offset:])
+		}
+		return d.decodeBase64(a.Type, d.appended[*a.Offset:d.end(*a.Offset)])
+	}
+	return nil, fmt.Errorf("Unknown format '%s'", a.Format)
+}
+
+// array returns the values of an encoded array of the header as a typed
+// slice, where appended arrays refer to the decoder's appended data.
+func (d *xmlDecoder) array(arr *darray) (interface{}, error) {
+	switch arr.Format {
+	case formatAscii:
+		return parseAscii(arr.Type, string(arr.Data))
+	case formatBinary:
+		return d.decodeBase64(arr.Type, arr.Data)
+	case formatAppended:
+		if arr.Offset == nil || *arr.Offset+arr.size > len(d.appended) {
+			return nil, fmt.Errorf("Invalid offset for appended data")
+		}
+		data := d.appended[*arr.Offset : *arr.Offset+arr.size]
+		if d.encoding == encodingRaw {
+			return d.decodeRaw(arr.Type, data)
+		}
+		return d.decodeBase64(arr.Type, data)
+	}
+	return nil, fmt.Errorf("Unknown format '%s'", arr.Format)
+}
+
+// end returns the end of the appended array starting at offset, i.e. the
+// next offset or the end of the appended data.
+func (d *xmlDecoder) end(offset int) int {
+	i := sort.SearchInts(d.offsets, offset+1)
+	if i < len(d.offsets) {
+		return d.offsets[i]
+	}
+	return len(d.appended)
+}
+
+// headerSize returns the size in bytes of a single header value.
+func (d *xmlDecoder) headerSize() int {
+	if d.header64 {
+		return 8
+	}
+	return 4
+}
+
+// rawSize returns the number of header values and the size in bytes of the
+// body of a raw binary array at the start of data.
+func (d *xmlDecoder) rawSize(data []byte) (int, int, error) {
+	p := newPayload()
+	p.header64 = d.header64
+	hs := d.headerSize()
+
+	// number of header values: 1 for uncompressed data, 3 + number of
+	// blocks for compressed data
+	nh := 1
+	if d.compressed {
+		if len(data) < hs {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		p.head.Write(data[:hs])
+		vals, err := p.headerValues()
+		if err != nil {
+			return 0, 0, err
+		}
+		if vals[0] > uint64(len(data)/hs) {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		nh = 3 + int(vals[0])
+	}
+
+	if len(data) < nh*hs {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	p.head.Reset()
+	p.head.Write(data[:nh*hs])
+
+	vals, err := p.headerValues()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var size uint64
+	if !d.compressed {
+		size = vals[0]
+	} else {
+		for _, s := range vals[3:] {
+			if s > uint64(len(data)) {
+				return 0, 0, io.ErrUnexpectedEOF
+			}
+			size += s
+		}
+	}
+
+	if uint64(len(data)-nh*hs) < size {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return nh, int(size), nil
+}
+
+// decodeRaw decodes a raw binary array from the start of data. The header is
+// read first to determine the length of the array within the data.
+func (d *xmlDecoder) decodeRaw(dtype string, data []byte) (interface{}, error) {
+	nh, size, err := d.rawSize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPayload()
+	p.header64 = d.header64
+	hs := d.headerSize()
+	p.head.Write(data[:nh*hs])
+	p.body.Write(data[nh*hs : nh*hs+size])
+	return d.values(dtype, p)
+}
+
+// decodeBase64 decodes a base64 encoded array. Uncompressed arrays are
+// encoded as a single base64 stream, while for compressed arrays the header
+// and body are encoded separately.
+func (d *xmlDecoder) decodeBase64(dtype string, data []byte) (interface{}, error) {
+	enc := base64.StdEncoding
+	data = []byte(strings.Join(strings.Fields(string(data)), ""))
+
+	if !d.compressed {
+		raw, err := enc.DecodeString(string(data))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeRaw(dtype, raw)
+	}
+
+	hs := d.headerSize()
+
+	// decode the number of blocks first to obtain the header's length
+	n := encodedLen(hs)
+	if len(data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	first, err := enc.DecodeString(string(data[:n]))
+	if err != nil {
+		return nil, err
+	}
+	p := newPayload()
+	p.header64 = d.header64
+	p.head.Write(first[:hs])
+	vals, err := p.headerValues()
+	if err != nil {
+		return nil, err
+	}
+	if vals[0] > uint64(len(data)/hs) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	nh := 3 + int(vals[0])
+
+	n = encodedLen(nh * hs)
+	if len(data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	head, err := enc.DecodeString(string(data[:n]))
+	if err != nil {
+		return nil, err
+	}
+	body, err := enc.DecodeString(string(data[n:]))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.decodeRaw(dtype, append(head[:nh*hs], body...))
+}
+
+// encodedLen returns the length of the padded base64 encoding of n bytes.
+func encodedLen(n int) int {
+	return base64.StdEncoding.EncodedLen(n)
+}
+
+// values decompresses the payload when required and converts the body to a
+// slice of the VTK type.
+func (d *xmlDecoder) values(dtype string, p *payload) (interface{}, error) {
+	if d.compressed {
+		var err error
+		if p, err = d.compressor.decompress(p); err != nil {
+			return nil, err
+		}
+	}
+
+	size, err := typeSize(dtype)
+	if err != nil {
+		return nil, err
+	}
+	if p.body.Len()%size != 0 {
+		msg := "Data of %d bytes does not distribute over %s"
+		return nil, fmt.Errorf(msg, p.body.Len(), dtype)
+	}
+
+	data, err := newSlice(dtype, p.body.Len()/size)
+	if err != nil {
+		return nil, err
+	}
+	if err := binary.Read(p.body, binary.LittleEndian, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// typeSize returns the size in bytes of a single value of the VTK type.
+func typeSize(dtype string) (int, error) {
+	switch dtype {
+	case "Int8", "UInt8":
+		return 1, nil
+	case "Int16", "UInt16":
+		return 2, nil
+	case "Int32", "UInt32", "Float32":
+		return 4, nil
+	case "Int64", "UInt64", "Float64":
+		return 8, nil
+	}
+	return 0, fmt.Errorf("%w: unknown data type '%s'", ErrUnsupportedType, dtype)
+}
+
+// newSlice returns a slice of length n matching the VTK type.
+func newSlice(dtype string, n int) (interface{}, error) {
+	switch dtype {
+	case "Int8":
+		return make([]int8, n), nil
+	case "UInt8":
+		return make([]uint8, n), nil
+	case "Int16":
+		return make([]int16, n), nil
+	case "UInt16":
+		return make([]uint16, n), nil
+	case "Int32":
+		return make([]int32, n), nil
+	case "UInt32":
+		return make([]uint32, n), nil
+	case "Int64":
+		return make([]int64, n), nil
+	case "UInt64":
+		return make([]uint64, n), nil
+	case "Float32":
+		return make([]float32, n), nil
+	case "Float64":
+		return make([]float64, n), nil
+	}
+	return nil, fmt.Errorf("%w: unknown data type '%s'", ErrUnsupportedType, dtype)
+}
+
+// parseAscii parses whitespace separated values into a slice of the VTK type.
+func parseAscii(dtype, data string) (interface{}, error) {
+	fields := strings.Fields(data)
+	res, err := newSlice(dtype, len(fields))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, s := range fields {
+		var err error
+		switch v := res.(type) {
+		case []int8:
+			var x int64
+			x, err = strconv.ParseInt(s, 10, 8)
+			v[i] = int8(x)
+		case []uint8:
+			var x uint64
+			x, err = strconv.ParseUint(s, 10, 8)
+			v[i] = uint8(x)
+		case []int16:
+			var x int64
+			x, err = strconv.ParseInt(s, 10, 16)
+			v[i] = int16(x)
+		case []uint16:
+			var x uint64
+			x, err = strconv.ParseUint(s, 10, 16)
+			v[i] = uint16(x)
+		case []int32:
+			var x int64
+			x, err = strconv.ParseInt(s, 10, 32)
+			v[i] = int32(x)
+		case []uint32:
+			var x uint64
+			x, err = strconv.ParseUint(s, 10, 32)
+			v[i] = uint32(x)
+		case []int64:
+			v[i], err = strconv.ParseInt(s, 10, 64)
+		case []uint64:
+			v[i], err = strconv.ParseUint(s, 10, 64)
+		case []float32:
+			var x float64
+			x, err = strconv.ParseFloat(s, 32)
+			v[i] = float32(x)
+		case []float64:
+			v[i], err = strconv.ParseFloat(s, 64)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// parseBounds parses the six integers of an extent attribute.
+func parseBounds(s string) (bounds, error) {
+	var b bounds
+	_, err := fmt.Sscan(s, &b[0], &b[1], &b[2], &b[3], &b[4], &b[5])
+	if err != nil {
+		return bounds{}, fmt.Errorf("Cannot parse extent '%s': %v", s, err)
+	}
+	return b, nil
+}
+
+// piece returns the piece with index i.
+func (h *Header) piece(i int) (*partition, error) {
+	if i < 0 || i >= len(h.Grid.Pieces) {
+		msg := "Piece %d out of range, number of pieces: %d"
+		return nil, fmt.Errorf(msg, i, len(h.Grid.Pieces))
+	}
+	return &h.Grid.Pieces[i], nil
+}
+
+// values returns the values of the field `name` in the data array.
+func (da *dataArray) values(name string) (interface{}, error) {
+	if da != nil {
+		if arr := da.lookup(name); arr != nil {
+			return da.decode(arr)
+		}
+	}
+	return nil, fmt.Errorf("No array named '%s'", name)
+}
+
+// NumPieces returns the number of pieces in the header.
+func (h *Header) NumPieces() int {
+	return len(h.Grid.Pieces)
+}
+
+// PointArray returns the values of the point data array `name` of the piece
+// as a typed slice, e.g. []float64.
+func (h *Header) PointArray(piece int, name string) (interface{}, error) {
+	p, err := h.piece(piece)
+	if err != nil {
+		return nil, err
+	}
+	return p.PointData.values(name)
+}
+
+// CellArray returns the values of the cell data array `name` of the piece
+// as a typed slice, e.g. []float64.
+func (h *Header) CellArray(piece int, name string) (interface{}, error) {
+	p, err := h.piece(piece)
+	if err != nil {
+		return nil, err
+	}
+	return p.CellData.values(name)
+}
+
+// FieldArray returns the values of the field data array `name` as a typed
+// slice, e.g. []float64.
+func (h *Header) FieldArray(name string) (interface{}, error) {
+	return h.Grid.Data.values(name)
+}
+
+// PointArrayNames returns the names of all point data arrays of the piece.
+func (h *Header) PointArrayNames(piece int) []string {
+	p, err := h.piece(piece)
+	if err != nil || p.PointData == nil {
+		return []string{}
+	}
+	return p.PointData.fieldNames()
+}
+
+// CellArrayNames returns the names of all cell data arrays of the piece.
+func (h *Header) CellArrayNames(piece int) []string {
+	p, err := h.piece(piece)
+	if err != nil || p.CellData == nil {
+		return []string{}
+	}
+	return p.CellData.fieldNames()
+}
+
+// FieldArrayNames returns the names of all field data arrays.
+func (h *Header) FieldArrayNames() []string {
+	if h.Grid.Data == nil {
+		return []string{}
+	}
+	return h.Grid.Data.fieldNames()
+}
+
+// PointCoordinates returns the points of the piece as a flat slice ordered
+// x, y, z per point. This applies to structured and unstructured grids and
+// poly data. For rectilinear grids refer to Coordinates.
+func (h *Header) PointCoordinates(piece int) (interface{}, error) {
+	p, err := h.piece(piece)
+	if err != nil {
+		return nil, err
+	}
+	return p.Points.values("Points")
+}
+
+// Coordinates returns the x, y, and z coordinates of a rectilinear grid's
+// piece. Missing coordinates are returned as nil.
+func (h *Header) Coordinates(piece int) (x, y, z interface{}, err error) {
+	p, err := h.piece(piece)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if p.Coordinates == nil {
+		return nil, nil, nil, fmt.Errorf("Piece %d has no coordinates", piece)
+	}
+
+	xyz := make([]interface{}, 3)
+	for i, dim := range []string{"x", "y", "z"} {
+		arr := p.Coordinates.lookup(dim + "_coordinates")
+		if arr == nil {
+			continue
+		}
+		if xyz[i], err = p.Coordinates.decode(arr); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return xyz[0], xyz[1], xyz[2], nil
+}
+
+// Connectivity returns the cells of an unstructured grid's piece. The slices
+// follow the convention of Cells, i.e. the offsets include a leading zero.
+func (h *Header) Connectivity(piece int) (conn, offset, types []int, err error) {
+	p, err := h.piece(piece)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if p.Cells == nil {
+		return nil, nil, nil, fmt.Errorf("Piece %d has no cells", piece)
+	}
+
+	conn, offset, err = cellArrays(p.Cells)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	offset = append([]int{0}, offset...)
+
+	values, err := p.Cells.values("types")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	types, err = toInts(values)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return conn, offset, types, nil
+}
@@ -0,0 +1,513 @@
+package govtk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formats lists the encoding and compression settings to round trip.
+var formats = map[string][]Option{
+	"ascii":             {Ascii()},
+	"binary":            {Binary()},
+	"binary_compressed": {Binary(), Compressed()},
+	"appended":          {Appended()},
+	"appended_compr":    {Appended(), Compressed()},
+	"raw":               {Raw()},
+	"raw_compressed":    {Raw(), Compressed()},
+	"binary_lz4":        {Binary(), CompressedWith(LZ4)},
+	"raw_lz4":           {Raw(), CompressedWith(LZ4)},
+	"appended_lzma":     {Appended(), CompressedWith(LZMA)},
+	"raw_lzma":          {Raw(), CompressedWith(LZMA)},
+}
+
+// roundTrip writes the header and reads it back.
+func roundTrip(t *testing.T, h *Header) *Header {
+	buf := new(bytes.Buffer)
+	if err := h.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReadUnstructured(t *testing.T) {
+	for name, opts := range formats {
+		t.Run(name, func(t *testing.T) {
+			vtu := newTetra(t, opts...)
+			if err := vtu.Add(FieldData("time", []float64{0.25})); err != nil {
+				t.Fatal(err)
+			}
+
+			r := roundTrip(t, vtu)
+			if r.Type != unstructuredGrid || r.NumPieces() != 1 {
+				t.Fatalf("Wrong type or pieces: %v %v", r.Type, r.NumPieces())
+			}
+
+			pts, err := r.PointCoordinates(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := []float64{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}
+			if !reflect.DeepEqual(pts, exp) {
+				t.Errorf("Wrong points: got %v, exp %v", pts, exp)
+			}
+
+			conn, offset, types, err := r.Connectivity(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(conn, []int{0, 1, 2, 3}) ||
+				!reflect.DeepEqual(offset, []int{0, 4}) ||
+				!reflect.DeepEqual(types, []int{Tetra}) {
+				t.Errorf("Wrong cells: %v %v %v", conn, offset, types)
+			}
+
+			checks := []struct {
+				get func() (interface{}, error)
+				exp []float64
+			}{
+				{func() (interface{}, error) { return r.PointArray(0, "temperature") },
+					[]float64{1, 2, 3, 4}},
+				{func() (interface{}, error) { return r.CellArray(0, "velocity") },
+					[]float64{1, 0, 0}},
+				{func() (interface{}, error) { return r.FieldArray("time") },
+					[]float64{0.25}},
+			}
+			for _, c := range checks {
+				got, err := c.get()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, c.exp) {
+					t.Errorf("Wrong data: got %v, exp %v", got, c.exp)
+				}
+			}
+
+			if r.PointArrayNames(0)[0] != "temperature" {
+				t.Errorf("Wrong point arrays: %v", r.PointArrayNames(0))
+			}
+			if _, err := r.PointArray(0, "missing"); err == nil {
+				t.Error("Missing array should return error")
+			}
+			if _, err := r.PointArray(1, "temperature"); err == nil {
+				t.Error("Missing piece should return error")
+			}
+
+			// the header read can be written again
+			again := roundTrip(t, r)
+			got, _ := again.PointArray(0, "temperature")
+			if !reflect.DeepEqual(got, []float64{1, 2, 3, 4}) {
+				t.Errorf("Wrong data after rewriting: %v", got)
+			}
+		})
+	}
+}
+
+func TestReadKeepsEncoding(t *testing.T) {
+	for name, opts := range formats {
+		t.Run(name, func(t *testing.T) {
+			vtu := newTetra(t, append([]Option{DataRange()}, opts...)...)
+			exp := new(bytes.Buffer)
+			if err := vtu.Write(exp); err != nil {
+				t.Fatal(err)
+			}
+
+			// the arrays are written as read, without encoding these again
+			r, err := Read(bytes.NewReader(exp.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := new(bytes.Buffer)
+			if err := r.Write(got); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), exp.Bytes()) {
+				t.Errorf("Output differs after reading:\n%s\n%s", got, exp)
+			}
+
+			// arrays added after reading are encoded as usual
+			err = r.Add(PointData("pressure", []float64{1, 2, 3, 4}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pressure, err := roundTrip(t, r).PointArray(0, "pressure")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := []float64{1, 2, 3, 4}; !reflect.DeepEqual(pressure, exp) {
+				t.Errorf("Wrong data: got %v, exp %v", pressure, exp)
+			}
+		})
+	}
+}
+
+func TestReadStructuredFormats(t *testing.T) {
+	for name, opts := range formats {
+		t.Run(name, func(t *testing.T) {
+			opts := append([]Option{WholeExtent(0, 2, 0, 1, 0, 0),
+				Spacing(0.5, 0.5, 1)}, opts...)
+
+			img, _ := Image(opts...)
+			f := []float64{1, 2, 3, 4, 5, 6}
+			if err := img.Add(PointData("f", f)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := roundTrip(t, img)
+			if r.Grid.Extent != img.Grid.Extent || r.Grid.Spacing != img.Grid.Spacing {
+				t.Errorf("Wrong grid: got %v, exp %v", r.Grid, img.Grid)
+			}
+			if r.format != img.format {
+				t.Errorf("Wrong format: got %v, exp %v", r.format, img.format)
+			}
+			got, err := r.PointArray(0, "f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, f) {
+				t.Errorf("Wrong data: got %v, exp %v", got, f)
+			}
+
+			vtr, _ := Rectilinear(opts...)
+			x, y := []float64{0, 1, 3}, []float64{-1, 1}
+			if err := vtr.Add(Points(x, y)); err != nil {
+				t.Fatal(err)
+			}
+			r = roundTrip(t, vtr)
+			gx, gy, gz, err := r.Coordinates(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gx, x) || !reflect.DeepEqual(gy, y) || gz != nil {
+				t.Errorf("Wrong coordinates: %v %v %v", gx, gy, gz)
+			}
+		})
+	}
+}
+
+func TestReadPolyData(t *testing.T) {
+	vtp, _ := PolyData(Raw(), Compressed())
+	err := vtp.Add(
+		Points([]float64{0, 0, 0, 1, 0, 0, 1, 1, 0}),
+		Polys([]int{0, 1, 2}, []int{0, 3}),
+		CellData("id", []float64{7}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := roundTrip(t, vtp)
+	lp := r.Grid.Pieces[0]
+	if lp.NumberOfPolys != 1 || lp.NumberOfCells != 1 || lp.Polys == nil {
+		t.Errorf("Wrong poly data piece: %+v", lp)
+	}
+	got, _ := r.CellArray(0, "id")
+	if !reflect.DeepEqual(got, []float64{7}) {
+		t.Errorf("Wrong data: got %v", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tetra.vtu")
+	if err := newTetra(t, Raw()).Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NumPieces() != 1 {
+		t.Errorf("Wrong number of pieces: %d", r.NumPieces())
+	}
+
+	if _, err := Open(filepath.Join(dir, "missing.vtu")); err == nil {
+		t.Error("Opening missing file should return error")
+	}
+}
+
+// Ensure files written by VTK itself, i.e. with UInt64 headers and multiple
+// compressed blocks, are decoded.
+func TestReadMultiBlockUInt64(t *testing.T) {
+	vals := []float64{1, 2, 3, 4, 5}
+	full := new(bytes.Buffer)
+	binaryWrite(t, full, vals)
+
+	// two blocks: 24 and 16 bytes
+	var blocks [][]byte
+	for _, b := range [][]byte{full.Bytes()[:24], full.Bytes()[24:]} {
+		p := newPayload()
+		p.body.Write(b)
+		c, err := zlibCompression{level: DefaultCompression}.compress(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, c.body.Bytes())
+	}
+
+	appended := new(bytes.Buffer)
+	header := []uint64{2, 24, 16, uint64(len(blocks[0])), uint64(len(blocks[1]))}
+	binaryWrite(t, appended, header)
+	appended.Write(blocks[0])
+	appended.Write(blocks[1])
+
+	doc := fmt.Sprintf(`<?xml version="1.0"?>
+<VTKFile type="ImageData" version="1.0" byte_order="LittleEndian" header_type="UInt64" compressor="vtkZLibDataCompressor">
+  <ImageData WholeExtent="0 4 0 0 0 0" Origin="0 0 0" Spacing="1 1 1">
+    <Piece Extent="0 4 0 0 0 0">
+      <PointData>
+        <DataArray type="Float64" Name="f" format="appended" offset="0"/>
+      </PointData>
+    </Piece>
+  </ImageData>
+  <AppendedData encoding="raw">
+   _%s
+  </AppendedData>
+</VTKFile>`, appended.String())
+
+	r, err := Read(bytes.NewBufferString(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.PointArray(0, "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, vals) {
+		t.Errorf("Wrong data: got %v, exp %v", got, vals)
+	}
+}
+
+// Ensure UInt64 headers are written for all formats, either requested
+// explicitly or promoted when a header value exceeds UInt32.
+func TestHeaderType64(t *testing.T) {
+	f := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+
+	for name, opts := range formats {
+		t.Run(name, func(t *testing.T) {
+			// requested before and after adding data
+			vti, err := Image(append([]Option{HeaderType64(),
+				WholeExtent(0, 1, 0, 1, 0, 1)}, opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := vti.Add(PointData("a", f)); err != nil {
+				t.Fatal(err)
+			}
+			late, _ := Image(append([]Option{WholeExtent(0, 1, 0, 1, 0, 1)},
+				opts...)...)
+			if err := late.Add(PointData("a", f), HeaderType64()); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, h := range []*Header{vti, late} {
+				if h.HeaderType != "UInt64" {
+					t.Errorf("Wrong header type: %v", h.HeaderType)
+				}
+				r := roundTrip(t, h)
+				got, err := r.PointArray(0, "a")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, f) {
+					t.Errorf("Wrong data: got %v, exp %v", got, f)
+				}
+			}
+		})
+	}
+
+	// a block size beyond UInt32 promotes the header type
+	opts := []Option{WholeExtent(0, 1, 0, 1, 0, 1), Appended(), Compressed(),
+		CompressionBlockSize(math.MaxUint32 + 1)}
+	vti, err := Image(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vti.Add(FieldData("time", []float64{1})); err != nil {
+		t.Fatal(err)
+	}
+	if err := vti.Add(PointData("a", f)); err != nil {
+		t.Fatal(err)
+	}
+	if vti.HeaderType != "UInt64" {
+		t.Errorf("Header type not promoted: %v", vti.HeaderType)
+	}
+	r := roundTrip(t, vti)
+	if got, _ := r.FieldArray("time"); !reflect.DeepEqual(got, []float64{1}) {
+		t.Errorf("Wrong field data after promotion: got %v", got)
+	}
+	if got, _ := r.PointArray(0, "a"); !reflect.DeepEqual(got, f) {
+		t.Errorf("Wrong data after promotion: got %v, exp %v", got, f)
+	}
+
+	// promotion encodes the data as added, not the caller's current data
+	buf := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+	vti, _ = Image(WholeExtent(0, 1, 0, 1, 0, 1), Compressed())
+	if err := vti.Add(PointData("a", buf)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range buf {
+		buf[i] = 9
+	}
+	if err := vti.Add(PointData("b", buf), HeaderType64()); err != nil {
+		t.Fatal(err)
+	}
+	r = roundTrip(t, vti)
+	exp := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+	if got, _ := r.PointArray(0, "a"); !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong data after promotion: got %v, exp %v", got, exp)
+	}
+
+	// ascii data has no payload header and is not encoded again
+	vti, _ = Image(WholeExtent(0, 1, 0, 1, 0, 1), Ascii())
+	if err := vti.Add(PointData("a", f)); err != nil {
+		t.Fatal(err)
+	}
+	data := vti.Grid.Pieces[0].PointData.Data[0].Data
+	if err := vti.Add(HeaderType64()); err != nil {
+		t.Fatal(err)
+	}
+	if &vti.Grid.Pieces[0].PointData.Data[0].Data[0] != &data[0] {
+		t.Errorf("Ascii data encoded again on promotion")
+	}
+}
+
+// Ensure all VTK types round trip with their sign and width preserved.
+func TestReadTypes(t *testing.T) {
+	datas := map[string]interface{}{
+		"Int8":    []int8{-128, 0, 127},
+		"UInt8":   []uint8{0, 1, 255},
+		"Int16":   []int16{-32768, 0, 32767},
+		"UInt16":  []uint16{0, 1, 65535},
+		"Int32":   []int32{-1 << 31, -7, 1<<31 - 1},
+		"UInt32":  []uint32{0, 1, 1<<32 - 1},
+		"Int64":   []int64{-1 << 63, -7, 1<<63 - 1},
+		"UInt64":  []uint64{0, 1, 1<<64 - 1},
+		"Float32": []float32{-1.5, 0, 3.25},
+		"Float64": []float64{-1.5, 0, 3.25},
+	}
+
+	for _, name := range []string{"ascii", "binary", "appended", "raw_compressed"} {
+		vtu, err := Unstructured(formats[name]...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vtu.Add(Points(make([]float64, 9))); err != nil {
+			t.Fatal(err)
+		}
+		for dtype, data := range datas {
+			if err := vtu.Add(PointData(dtype, data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := vtu.Add(PointData("int", []int{-1, 0, 1})); err != nil {
+			t.Fatal(err)
+		}
+
+		r := roundTrip(t, vtu)
+		for dtype, data := range datas {
+			got, err := r.PointArray(0, dtype)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Errorf("%s: wrong %s data: got %v, exp %v",
+					name, dtype, got, data)
+			}
+			if typ := r.Grid.Pieces[0].PointData.lookup(dtype).Type; typ != dtype {
+				t.Errorf("%s: wrong type: got %v, exp %v", name, typ, dtype)
+			}
+		}
+
+		got, _ := r.PointArray(0, "int")
+		if !reflect.DeepEqual(got, []int64{-1, 0, 1}) {
+			t.Errorf("%s: wrong int data: got %v", name, got)
+		}
+	}
+}
+
+// Ensure truncated or garbage compressed headers return errors.
+func TestReadMalformedHeader(t *testing.T) {
+	// number of blocks overflowing int, and garbage following it
+	blocks := make([]byte, 32)
+	binary.LittleEndian.PutUint64(blocks, 1<<63|1<<31)
+	garbage := bytes.Repeat([]byte{0xff}, 32)
+
+	tests := map[string][]byte{
+		"empty":     {},
+		"truncated": {2, 0, 0, 0, 0, 0},
+		"blocks":    blocks,
+		"garbage":   garbage,
+	}
+	for _, header64 := range []bool{false, true} {
+		d := &xmlDecoder{header64: header64, compressed: true,
+			compressor: zlibCompression{}}
+		for name, data := range tests {
+			if _, err := d.decodeRaw("Float64", data); err == nil {
+				t.Errorf("Expected error for %s raw header", name)
+			}
+			enc := []byte(base64.StdEncoding.EncodeToString(data))
+			if _, err := d.decodeBase64("Float64", enc); err == nil {
+				t.Errorf("Expected error for %s base64 header", name)
+			}
+		}
+	}
+}
+
+// Ensure unknown file types and undecodable coordinates return errors.
+func TestReadInvalid(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<VTKFile type="%s" version="1.0" byte_order="LittleEndian">
+  <RectilinearGrid WholeExtent="0 1 0 0 0 0">
+    <Piece Extent="0 1 0 0 0 0">
+      <Coordinates>
+        <DataArray type="Float64" Name="x_coordinates" format="ascii">0 %s</DataArray>
+      </Coordinates>
+    </Piece>
+  </RectilinearGrid>
+</VTKFile>`
+
+	for _, typ := range []string{"", "RectilinearGrd", "PRectilinearGrid"} {
+		if _, err := Read(strings.NewReader(fmt.Sprintf(doc, typ, "1"))); err == nil {
+			t.Errorf("Expected error for file type '%s'", typ)
+		}
+	}
+
+	r, err := Read(strings.NewReader(fmt.Sprintf(doc, "RectilinearGrid", "1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y, z, err := r.Coordinates(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(x, []float64{0, 1}) || y != nil || z != nil {
+		t.Errorf("Wrong coordinates: %v %v %v", x, y, z)
+	}
+
+	r, err = Read(strings.NewReader(fmt.Sprintf(doc, "RectilinearGrid", "one")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := r.Coordinates(0); err == nil {
+		t.Errorf("Expected error for undecodable coordinates")
+	}
+}
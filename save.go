@@ -0,0 +1,125 @@
+package govtk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// Sync ensures Save syncs the file to disk before it is renamed to its final
+// name, and syncs the directory after the rename, such that the file survives
+// a system crash once Save returns.
+func Sync() Option {
+	return func(h *Header) error {
+		h.sync = true
+		return nil
+	}
+}
+
+// SyncPVD ensures the PVD collection and all data files written by Add are
+// synced to disk, see Sync().
+func SyncPVD() PVDOption {
+	return func(pvd *PVD) error {
+		pvd.sync = true
+		return nil
+	}
+}
+
+// saveFile atomically writes the file: the content is written to a temporary
+// file in the same directory, which is flushed, optionally synced to disk,
+// and renamed to filename. When synced, the directory is synced after the
+// rename to persist the rename itself. On failure the temporary file is
+// removed, leaving any existing file untouched. New files get permissions
+// 0666 reduced by the umask, similar to os.Create, while the permissions of
+// an existing file are kept.
+func saveFile(filename string, sync bool, write func(w io.Writer) error) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := createTemp(dir, base)
+	if err != nil {
+		return err
+	}
+
+	// remove the temporary file, unless renamed
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if err := writeFile(f, sync, write); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(filename); err == nil {
+		if err := os.Chmod(tmp, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return err
+	}
+	if sync {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// createTemp creates a new temporary file next to base in dir. In contrast to
+// ioutil.TempFile, which uses permissions 0600, the file is created with
+// permissions 0666 before the umask, as done by os.Create.
+func createTemp(dir, base string) (*os.File, error) {
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, "."+base+".tmp"+strconv.Itoa(int(rand.Uint32())))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, fmt.Errorf("Cannot create temporary file for %s in %s", base, dir)
+}
+
+// syncDir syncs the directory to disk, which persists the entries of the
+// directory, e.g. a renamed file. Windows does not support syncing
+// directories, where renames are persisted by the file system itself.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
+
+// writeFile writes to f through a buffered writer, flushes the buffer, and
+// syncs the file to disk if requested.
+func writeFile(f *os.File, sync bool, write func(w io.Writer) error) error {
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if sync {
+		return f.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package govtk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Ensure saved files equal the written output, including the tail of the
+// buffered writer.
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	vtu := newTetra(t, Raw(), Sync())
+	if err := vtu.Add(PointData("big", make([]float64, 10000))); err != nil {
+		t.Fatal(err)
+	}
+	exp := new(bytes.Buffer)
+	if err := vtu.Write(exp); err != nil {
+		t.Fatal(err)
+	}
+
+	// the extension is appended when missing
+	if err := vtu.Save(filepath.Join(dir, "tetra")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "tetra.vtu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, exp.Bytes()) {
+		t.Errorf("Saved file differs: got %d bytes, exp %d", len(got), exp.Len())
+	}
+
+	// the collection and its data files are saved
+	pvd, err := NewPVD(Directory(dir), SyncPVD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pvd.Add(vtu, Time(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pvd.Save(filepath.Join(dir, "sim")); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := OpenPVD(filepath.Join(dir, "sim.pvd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 1 {
+		t.Errorf("Wrong length: got %v, exp %v", restored.Len(), 1)
+	}
+
+	// the directory is synced after renaming
+	if err := syncDir(dir); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := syncDir(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected error syncing a missing directory")
+	}
+}
+
+// Ensure a failing save leaves the existing file untouched.
+func TestSaveFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govtk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.vtu")
+	if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	errWrite := errors.New("write failed")
+	err = saveFile(path, false, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Errorf("Expected write error, got: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Errorf("Existing file modified: %q", got)
+	}
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected a single file, got %d", len(files))
+	}
+
+	// the permissions of an existing file are kept
+	if err := saveFile(path, false, func(w io.Writer) error {
+		_, err := w.Write([]byte("new"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Wrong permissions: got %v, exp %v", info.Mode().Perm(), 0600)
+	}
+
+	// new files get the permissions of os.Create
+	created := filepath.Join(dir, "created.vtu")
+	f, err := os.Create(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	exp, err := os.Stat(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "saved.vtu")
+	if err := saveFile(saved, false, func(w io.Writer) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = os.Stat(saved); err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != exp.Mode().Perm() {
+		t.Errorf("Wrong permissions: got %v, exp %v", info.Mode().Perm(),
+			exp.Mode().Perm())
+	}
+}
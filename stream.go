@@ -0,0 +1,348 @@
+package govtk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Streaming writes the data of all arrays as appended data, where the data
+// is only encoded and compressed while writing. The XML structure is written
+// first, followed by the arrays, which are written block by block directly
+// to the io.Writer. This limits the memory usage of the encoded data to a
+// single block rather than the whole file. The offsets of compressed arrays
+// are computed up front by compressing the data when it is added, such that
+// compressed arrays are compressed twice: once when added and once while
+// writing. Note, the data provided is not copied and must not be modified
+// before writing. Streaming is required to be set before adding points,
+// cells, or data.
+func Streaming() Option {
+	return func(h *Header) error {
+		if h.format == formatAscii {
+			msg := "Cannot use streaming with format '%v'"
+			return fmt.Errorf(msg, h.format)
+		}
+		if len(h.Grid.Pieces) > 0 || h.Grid.Data != nil {
+			return fmt.Errorf("Streaming should be set before adding points, cells, or data")
+		}
+		h.streaming = true
+		h.setAppendedData()
+		h.setHeaderType()
+		return nil
+	}
+}
+
+// stream holds an array to be encoded while writing the appended data. The
+// payload header is computed up front, such that the size of the encoded
+// array is known before writing.
+type stream struct {
+	values   interface{}
+	encoding string
+	header   []uint64
+	header64 bool
+
+	// compressor is nil for uncompressed data
+	compressor blockCompressor
+}
+
+// newStream returns the stream of the data for the dataArray's settings. For
+// compressed data the data is compressed once to compute the block sizes.
+// Single values are streamed as a slice of one value.
+func (da *dataArray) newStream(data interface{}) (*stream, error) {
+	data = asSlice(data)
+	s := &stream{
+		values:   data,
+		encoding: da.appended.Encoding,
+		header64: da.header64,
+	}
+
+	n, err := byteSize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bc, ok := da.compressor.(blockCompressor)
+	if !ok {
+		s.header = []uint64{uint64(n)}
+		return s, s.checkHeader()
+	}
+
+	s.compressor = bc
+	bs := bc.bytesPerBlock()
+	s.header = []uint64{0, uint64(bs), uint64(n % bs)}
+	err = compressedBlocks(data, bc, func(block []byte) error {
+		s.header[0]++
+		s.header = append(s.header, uint64(len(block)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, s.checkHeader()
+}
+
+// checkHeader returns errHeaderOverflow when the header values do not fit
+// the UInt32 header type.
+func (s *stream) checkHeader() error {
+	if s.header64 {
+		return nil
+	}
+	for _, v := range s.header {
+		if v > math.MaxUint32 {
+			return fmt.Errorf("%w: %d", errHeaderOverflow, v)
+		}
+	}
+	return nil
+}
+
+// headerSize returns the number of bytes of the header.
+func (s *stream) headerSize() int {
+	if s.header64 {
+		return 8 * len(s.header)
+	}
+	return 4 * len(s.header)
+}
+
+// bodySize returns the number of bytes of the, possibly compressed, data.
+func (s *stream) bodySize() int {
+	if s.compressor == nil {
+		return int(s.header[0])
+	}
+	n := 0
+	for _, size := range s.header[3:] {
+		n += int(size)
+	}
+	return n
+}
+
+// size returns the number of bytes of the encoded array.
+func (s *stream) size() int {
+	if s.encoding == encodingRaw {
+		return s.headerSize() + s.bodySize()
+	}
+
+	// uncompressed data is encoded as a single base64 stream, while for
+	// compressed data the header and data are encoded separately
+	enc := base64.StdEncoding
+	if s.compressor == nil {
+		return enc.EncodedLen(s.headerSize() + s.bodySize())
+	}
+	return enc.EncodedLen(s.headerSize()) + enc.EncodedLen(s.bodySize())
+}
+
+// write encodes the array towards w. Similar to the base64er, compressed
+// data encodes the header and data separately.
+func (s *stream) write(w io.Writer) error {
+	p := newPayload()
+	p.header64 = s.header64
+	if err := p.setHeaderValues(s.header); err != nil {
+		return err
+	}
+
+	if s.encoding == encodingRaw {
+		if _, err := w.Write(p.head.Bytes()); err != nil {
+			return err
+		}
+		return s.writeBody(w)
+	}
+
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := enc.Write(p.head.Bytes()); err != nil {
+		return err
+	}
+	if s.compressor != nil {
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		enc = base64.NewEncoder(base64.StdEncoding, w)
+	}
+	if err := s.writeBody(enc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writeBody writes the, possibly compressed, data block by block towards w.
+// The compressed block sizes are verified against the header.
+func (s *stream) writeBody(w io.Writer) error {
+	if s.compressor == nil {
+		return blocks(s.values, DefaultBlockSize, func(block []byte) error {
+			_, err := w.Write(block)
+			return err
+		})
+	}
+
+	i := 3
+	return compressedBlocks(s.values, s.compressor, func(block []byte) error {
+		if i >= len(s.header) || uint64(len(block)) != s.header[i] {
+			return fmt.Errorf("%w: data changed after adding", ErrCompression)
+		}
+		i++
+		_, err := w.Write(block)
+		return err
+	})
+}
+
+// appendStream appends the stream to the appended data and sets the array's
+// offset accordingly.
+func (da *dataArray) appendStream(arr *darray, s *stream) {
+	// appended data is required to start with underscore ("_")
+	if len(da.appended.Data) == 0 {
+		da.appended.Data = []byte("_")
+	}
+
+	arr.Offset = new(int)
+	*arr.Offset = da.appended.streamed
+	da.appended.streamed += s.size()
+	da.appended.streams = append(da.appended.streams, s)
+}
+
+// writeStreamed writes the XML structure, where the appended data is
+// encoded while writing.
+func (h *Header) writeStreamed(w io.Writer) error {
+	buf := new(bytes.Buffer)
+	if h.format != formatRaw {
+		buf.WriteString(xml.Header)
+	}
+	if err := xml.NewEncoder(buf).Encode(h); err != nil {
+		return err
+	}
+
+	// split the structure directly after the underscore
+	skeleton := buf.Bytes()
+	i := bytes.LastIndex(skeleton, []byte(">_</AppendedData>"))
+	if i < 0 {
+		if h.Appended != nil && len(h.Appended.streams) > 0 {
+			return fmt.Errorf("%w: missing appended data for %d streams",
+				ErrEncoding, len(h.Appended.streams))
+		}
+		_, err := w.Write(skeleton)
+		return err
+	}
+	i += len(">_")
+
+	if _, err := w.Write(skeleton[:i]); err != nil {
+		return err
+	}
+	for _, s := range h.Appended.streams {
+		if err := s.write(w); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(skeleton[i:])
+	return err
+}
+
+// asSlice returns a single value as a slice holding the value, while slices
+// are returned as is.
+func asSlice(data interface{}) interface{} {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() || v.Kind() == reflect.Slice {
+		return data
+	}
+	s := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+	s.Index(0).Set(v)
+	return s.Interface()
+}
+
+// byteSize returns the number of bytes of the data slice in binary format.
+func byteSize(data interface{}) (int, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("%w: %T is not a slice", ErrUnsupportedType, data)
+	}
+
+	one := reflect.MakeSlice(v.Type(), 1, 1).Interface()
+	size := binary.Size(fixedSize(one))
+	if size <= 0 {
+		return 0, fmt.Errorf("%w: %T has no binary size", ErrUnsupportedType, data)
+	}
+	return size * v.Len(), nil
+}
+
+// blocks converts the data to its little endian binary representation and
+// calls fn for each block of blockSize bytes. Only the last block might be
+// smaller. The data is converted in parts, such that at most two blocks are
+// kept in memory.
+func blocks(data interface{}, blockSize int, fn func(block []byte) error) error {
+	n, err := byteSize(data)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Len() == 0 {
+		return nil
+	}
+	size := n / v.Len()
+	step := (blockSize + size - 1) / size
+
+	buf := new(bytes.Buffer)
+	for i := 0; i < v.Len(); i += step {
+		j := i + step
+		if j > v.Len() {
+			j = v.Len()
+		}
+
+		part := fixedSize(v.Slice(i, j).Interface())
+		if err := binary.Write(buf, binary.LittleEndian, part); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncoding, err)
+		}
+
+		for buf.Len() >= blockSize {
+			if err := fn(buf.Next(blockSize)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if buf.Len() > 0 {
+		return fn(buf.Bytes())
+	}
+	return nil
+}
+
+// compressedBlocks converts the data in blocks, see blocks, and compresses
+// these using the compressor's workers. The function fn is called for each
+// compressed block in order. At most one block per worker is kept in memory.
+func compressedBlocks(data interface{}, bc blockCompressor,
+	fn func(block []byte) error) error {
+	workers := bc.numWorkers()
+
+	var pending [][]byte
+	flush := func() error {
+		out, err := compressAll(pending, workers, bc.compressBlock)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCompression, err)
+		}
+		pending = pending[:0]
+
+		for _, b := range out {
+			if err := fn(b.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := blocks(data, bc.bytesPerBlock(), func(block []byte) error {
+		// the block is only valid during this call
+		if workers > 1 {
+			block = append([]byte(nil), block...)
+		}
+		pending = append(pending, block)
+		if len(pending) < workers {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,159 @@
+package govtk
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Ensure streamed files are identical to files encoded while adding data.
+func TestStreaming(t *testing.T) {
+	settings := map[string][]Option{
+		"appended":       {Appended()},
+		"appended_compr": {Appended(), Compressed()},
+		"raw":            {Raw()},
+		"raw_compressed": {Raw(), Compressed()},
+		"raw_lz4":        {Raw(), CompressedWith(LZ4)},
+		"appended_lzma":  {Appended(), CompressedWith(LZMA)},
+		"raw_blocks":     {Raw(), Compressed(), CompressionBlockSize(12)},
+		"raw_header64":   {Raw(), Compressed(), HeaderType64()},
+		"raw_workers": {Raw(), Compressed(), CompressionBlockSize(64),
+			CompressionWorkers(4)},
+	}
+
+	for name, opts := range settings {
+		t.Run(name, func(t *testing.T) {
+			var out [][]byte
+			for _, streaming := range []bool{false, true} {
+				opts := append([]Option{}, opts...)
+				if streaming {
+					opts = append(opts, Streaming())
+				}
+
+				vtu := newTetra(t, opts...)
+				err := vtu.Add(FieldData("time", []float64{0.5}),
+					FieldData("step", 3), FieldData("dt", float32(0.1)),
+					PointData("ids", []int32{-1, 2, 3, 4}),
+					PointData("big", make([]uint64, 4*100)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				buf := new(bytes.Buffer)
+				if err := vtu.Write(buf); err != nil {
+					t.Fatal(err)
+				}
+				out = append(out, buf.Bytes())
+			}
+
+			if !bytes.Equal(out[0], out[1]) {
+				t.Errorf("Streamed file differs:\n%s\n%s", out[0], out[1])
+			}
+
+			// streamed files are read back
+			r, err := Read(bytes.NewReader(out[1]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.PointArray(0, "ids")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := []int32{-1, 2, 3, 4}; !reflect.DeepEqual(got, exp) {
+				t.Errorf("Wrong data: got %v, exp %v", got, exp)
+			}
+		})
+	}
+}
+
+func TestStreamingSettings(t *testing.T) {
+	// streaming keeps the encoded data out of memory
+	vti, err := Image(WholeExtent(0, 1, 0, 1, 0, 1), Streaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := make([]float64, 8)
+	if err := vti.Add(PointData("a", a)); err != nil {
+		t.Fatal(err)
+	}
+	if string(vti.Appended.Data) != "_" || len(vti.Appended.streams) != 1 {
+		t.Errorf("Appended data is not streamed: %q", vti.Appended.Data)
+	}
+
+	// the streamed data refers to the caller's data, without a copy
+	arr := vti.Grid.Pieces[0].PointData.Data[0]
+	for _, values := range []interface{}{arr.values,
+		vti.Appended.streams[0].values} {
+		if v, ok := values.([]float64); !ok || &v[0] != &a[0] {
+			t.Errorf("Streamed data is copied")
+		}
+	}
+
+	if _, err := Image(Ascii(), Streaming()); err == nil {
+		t.Errorf("Expected error for streaming ascii data")
+	}
+
+	// streaming after adding appended data would mix up the offsets
+	vtu := newTetra(t, Appended())
+	if err := vtu.Add(Streaming()); err == nil {
+		t.Errorf("Expected error for streaming after adding data")
+	}
+	vti, _ = Image(WholeExtent(0, 1, 0, 1, 0, 1), Appended())
+	if err := vti.Add(FieldData("time", 1.5), Streaming()); err == nil {
+		t.Errorf("Expected error for streaming after adding field data")
+	}
+
+	// streams are not dropped without appended data to write these into
+	vti, _ = Image(WholeExtent(0, 1, 0, 1, 0, 1), Streaming())
+	if err := vti.Add(FieldData("time", 1.5)); err != nil {
+		t.Fatal(err)
+	}
+	vti.Appended.Data = nil
+	err = vti.Write(new(bytes.Buffer))
+	if !errors.Is(err, ErrEncoding) {
+		t.Errorf("Expected encoding error, got: %v", err)
+	}
+
+	// modifying compressed data before writing is detected
+	vti, _ = Image(WholeExtent(0, 1, 0, 1, 0, 1), Streaming(), Compressed())
+	data := make([]float64, 1000)
+	if err := vti.Add(FieldData("a", data)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		data[i] = float64(i) * 1.1
+	}
+	err = vti.Write(new(bytes.Buffer))
+	if !errors.Is(err, ErrCompression) {
+		t.Errorf("Expected compression error, got: %v", err)
+	}
+}
+
+// Ensure blocks splits the binary data in blocks of equal size.
+func TestBlocks(t *testing.T) {
+	data := []int{1, 2, 3, 4, 5}
+	exp := new(bytes.Buffer)
+	binaryWrite(t, exp, fixedSize(data))
+
+	for _, size := range []int{1, 3, 8, 12, 40, 100} {
+		got := new(bytes.Buffer)
+		var sizes []int
+		err := blocks(data, size, func(block []byte) error {
+			sizes = append(sizes, len(block))
+			got.Write(block)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), exp.Bytes()) {
+			t.Errorf("Wrong data for block size %d: %x", size, got.Bytes())
+		}
+		for i, n := range sizes {
+			if n != size && (i != len(sizes)-1 || n > size) {
+				t.Errorf("Wrong block sizes for block size %d: %v", size, sizes)
+			}
+		}
+	}
+}
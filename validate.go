@@ -0,0 +1,286 @@
+package govtk
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Validate checks the consistency of the mesh before writing, as invalid
+// meshes are written without complaints but fail to load in e.g. ParaView.
+// For each piece it verifies:
+//   - the piece's extent lies inside the whole extent;
+//   - the connectivity only refers to existing points;
+//   - the offsets are increasing and consistent with the connectivity;
+//   - the number of nodes of each cell matches its cell type, where
+//     the HigherOrderDegrees determine those of Lagrange and Bezier cells;
+//   - the points, coordinates, and point and cell data match the number of
+//     points and cells of the piece;
+//   - the active attributes, e.g. Vectors, refer to existing arrays.
+//
+// Image data additionally requires the spacing to be set. The returned error
+// wraps ErrInvalidMesh.
+func (h *Header) Validate() error {
+	if err := h.validateGrid(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMesh, err)
+	}
+	for i := range h.Grid.Pieces {
+		if err := h.validatePiece(&h.Grid.Pieces[i]); err != nil {
+			return fmt.Errorf("%w: piece %d: %v", ErrInvalidMesh, i, err)
+		}
+	}
+	return nil
+}
+
+// validateGrid checks the properties of the grid shared by all pieces.
+func (h *Header) validateGrid() error {
+	switch h.Type {
+	case imageData, rectilinearGrid, structuredGrid:
+		if h.Grid.Extent == (bounds{}) {
+			return fmt.Errorf("%s has no or empty extent", h.Type)
+		}
+	}
+	if h.Type == imageData && h.Grid.Spacing == "" {
+		return fmt.Errorf("%s has no spacing", h.Type)
+	}
+	return nil
+}
+
+// validatePiece checks the topology and the data of a single piece.
+func (h *Header) validatePiece(p *partition) error {
+	switch h.Type {
+	case imageData, rectilinearGrid, structuredGrid:
+		if err := h.Grid.Extent.contains(p.Extent); err != nil {
+			return err
+		}
+	}
+
+	if err := checkLength(p.Points, "Points", 3*p.NumberOfPoints); err != nil {
+		return err
+	}
+	if err := checkCoordinates(p); err != nil {
+		return err
+	}
+	if err := checkCells(p); err != nil {
+		return err
+	}
+
+	topology := []struct {
+		name string
+		arr  *dataArray
+		n    int
+	}{
+		{"Verts", p.Verts, p.NumberOfVerts},
+		{"Lines", p.Lines, p.NumberOfLines},
+		{"Strips", p.Strips, p.NumberOfStrips},
+		{"Polys", p.Polys, p.NumberOfPolys},
+	}
+	for _, top := range topology {
+		if top.arr == nil {
+			continue
+		}
+		_, err := checkConnectivity(top.arr, p.NumberOfPoints, top.n)
+		if err != nil {
+			return fmt.Errorf("%s: %v", top.name, err)
+		}
+	}
+
+	if err := checkData(p.PointData, "PointData", p.NumberOfPoints); err != nil {
+		return err
+	}
+	if err := checkData(p.CellData, "CellData", p.NumberOfCells); err != nil {
+		return err
+	}
+	if err := checkAttributes(p.PointData, "PointData"); err != nil {
+		return err
+	}
+	return checkAttributes(p.CellData, "CellData")
+}
+
+// contains returns an error when the extent b does not lie inside the
+// extent of the whole grid.
+func (whole bounds) contains(b bounds) error {
+	for i := 0; i < len(b); i += 2 {
+		if b[i] < whole[i] || b[i+1] > whole[i+1] {
+			msg := "Extent %v outside of WholeExtent %v"
+			return fmt.Errorf(msg, b, whole)
+		}
+	}
+	return nil
+}
+
+// checkCells checks the cells of an unstructured grid: the connectivity and
+// offsets, and the number of nodes of each cell compared to its type.
+func checkCells(p *partition) error {
+	if p.Cells == nil {
+		return nil
+	}
+
+	offsets, err := checkConnectivity(p.Cells, p.NumberOfPoints,
+		p.NumberOfCells)
+	if err != nil {
+		return err
+	}
+
+	values, err := p.Cells.values("types")
+	if err != nil {
+		return err
+	}
+	types, err := toInts(values)
+	if err != nil {
+		return err
+	}
+	if len(types) != p.NumberOfCells {
+		msg := "Number of types %d does not match number of cells %d"
+		return fmt.Errorf(msg, len(types), p.NumberOfCells)
+	}
+
+	degrees, err := cellDegrees(p)
+	if err != nil {
+		return err
+	}
+
+	start, polyhedra := 0, false
+	for i, t := range types {
+		n := offsets[i] - start
+		start = offsets[i]
+
+		c, ok := LookupCell(t)
+		if !ok {
+			return fmt.Errorf("Cell %d has unknown type %d", i, t)
+		}
+		if c.Nodes < 0 && degrees != nil {
+			deg := [3]int{degrees[3*i], degrees[3*i+1], degrees[3*i+2]}
+			c.Nodes = higherOrderNodes(t, deg)
+		}
+		if (c.Nodes < 0 && n == 0) || (c.Nodes >= 0 && n != c.Nodes) {
+			msg := "Cell %d of type %s has %d nodes, exp %d"
+			return fmt.Errorf(msg, i, c.Name, n, c.Nodes)
+		}
+		polyhedra = polyhedra || t == Polyhedron
+	}
+
+	if polyhedra {
+		return checkFaces(p)
+	}
+	return nil
+}
+
+// checkFaces checks the faces of the polyhedra are present and, for the
+// layout of VTK 9, only refer to existing points.
+func checkFaces(p *partition) error {
+	values, err := p.Cells.values("face_connectivity")
+	if err != nil {
+		if p.Cells.lookup("faces") == nil {
+			return fmt.Errorf("Polyhedron without faces")
+		}
+		return nil
+	}
+	conn, err := toInts(values)
+	if err != nil {
+		return err
+	}
+	for i, c := range conn {
+		if c < 0 || c >= p.NumberOfPoints {
+			msg := "Face connectivity %d refers to point %d, number of points %d"
+			return fmt.Errorf(msg, i, c, p.NumberOfPoints)
+		}
+	}
+	return nil
+}
+
+// checkConnectivity checks the connectivity and offsets arrays of the data
+// array against the number of points and the expected number of cells, and
+// returns the offsets. The offsets, excluding the leading zero, are required
+// to be non-decreasing and to end at the length of the connectivity.
+func checkConnectivity(da *dataArray, points, cells int) ([]int, error) {
+	conn, offsets, err := cellArrays(da)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range conn {
+		if c < 0 || c >= points {
+			msg := "Connectivity %d refers to point %d, number of points %d"
+			return nil, fmt.Errorf(msg, i, c, points)
+		}
+	}
+
+	if len(offsets) != cells {
+		msg := "Number of offsets %d does not match number of cells %d"
+		return nil, fmt.Errorf(msg, len(offsets), cells)
+	}
+	prev := 0
+	for i, o := range offsets {
+		if o < prev {
+			msg := "Offsets decrease at cell %d: %d < %d"
+			return nil, fmt.Errorf(msg, i, o, prev)
+		}
+		prev = o
+	}
+	if prev != len(conn) {
+		msg := "Last offset %d does not match connectivity length %d"
+		return nil, fmt.Errorf(msg, prev, len(conn))
+	}
+	return offsets, nil
+}
+
+// checkCoordinates checks the coordinates of a rectilinear grid against the
+// number of points of the piece's extent in each dimension.
+func checkCoordinates(p *partition) error {
+	if p.Coordinates == nil {
+		return nil
+	}
+	for i, arr := range p.Coordinates.Data {
+		n := p.Extent[2*i+1] - p.Extent[2*i] + 1
+		if err := checkValues(p.Coordinates, arr, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLength checks the length of the single array of the data array.
+func checkLength(da *dataArray, name string, n int) error {
+	if da == nil {
+		return nil
+	}
+	for _, arr := range da.Data {
+		if err := checkValues(da, arr, n); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// checkData checks the length of each array against its number of
+// components times the number of points or cells.
+func checkData(da *dataArray, name string, n int) error {
+	if da == nil {
+		return nil
+	}
+	for _, arr := range da.Data {
+		err := checkValues(da, arr, arr.NumberOfComponents*n)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// checkValues checks the number of values of the array, which is decoded
+// from the data array.
+func checkValues(da *dataArray, arr *darray, n int) error {
+	values, err := da.decode(arr)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	if v.Len() != n {
+		msg := "Array '%s' has %d values, exp %d"
+		return fmt.Errorf(msg, arr.Name, v.Len(), n)
+	}
+	return nil
+}
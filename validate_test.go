@@ -0,0 +1,93 @@
+package govtk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	coords := []float64{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}
+	x := []float64{0, 1, 2}
+
+	tests := []struct {
+		name  string
+		mesh  func() (*Header, error)
+		valid bool
+	}{
+		{"tetra", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Tetra}))
+		}, true},
+		{"polygon", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Polygon}))
+		}, true},
+		{"connectivity", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 4}, []int{0, 4}, []int{Tetra}))
+		}, false},
+		{"offsets_decrease", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 3}, []int{0, 3, 2, 4},
+					[]int{Triangle, Vertex, Line}))
+		}, false},
+		{"offsets_length", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 3}, []int{0, 3}, []int{Triangle}))
+		}, false},
+		{"nodes", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Hexahedron}))
+		}, false},
+		{"unknown_type", func() (*Header, error) {
+			return Unstructured(Points(coords),
+				Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{99}))
+		}, false},
+		{"polys", func() (*Header, error) {
+			return PolyData(Points(coords), Polys([]int{0, 1, 4}, []int{3}))
+		}, false},
+		{"image", func() (*Header, error) {
+			return Image(WholeExtent(0, 2, 0, 2, 0, 2), Spacing(1, 1, 1),
+				Piece(Extent(0, 1, 0, 2, 0, 2)))
+		}, true},
+		{"image_spacing", func() (*Header, error) {
+			return Image(WholeExtent(0, 2, 0, 2, 0, 2))
+		}, false},
+		{"image_extent", func() (*Header, error) {
+			return Image(WholeExtent(0, 2, 0, 2, 0, 2), Spacing(1, 1, 1),
+				Piece(Extent(0, 3, 0, 2, 0, 2)))
+		}, false},
+		{"coordinates", func() (*Header, error) {
+			return Rectilinear(WholeExtent(0, 2, 0, 2, 0, 2),
+				Piece(Extent(0, 1, 0, 2, 0, 2)), Points(x, x, x))
+		}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := test.mesh()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.Validate()
+			if test.valid && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidMesh) {
+				t.Errorf("Expected invalid mesh, got: %v", err)
+			}
+		})
+	}
+}
+
+// Ensure Cells requires a type for each cell.
+func TestCellsLength(t *testing.T) {
+	vtu, err := Unstructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vtu.Add(Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Tetra, Tetra}))
+	if err == nil {
+		t.Error("Expected error for mismatching offsets and labels")
+	}
+}
@@ -1,13 +1,12 @@
 package govtk
 
 import (
-	"bufio"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 )
 
 const (
@@ -16,6 +15,7 @@ const (
 	rectilinearGrid  = "RectilinearGrid"
 	structuredGrid   = "StructuredGrid"
 	unstructuredGrid = "UnstructuredGrid"
+	polyData         = "PolyData"
 
 	// Data format representations
 	formatAscii    = "ascii"
@@ -28,8 +28,20 @@ const (
 	encodingRaw    = "raw"
 	encodingBase64 = "base64"
 
-	// Identifier for zlib compressed data in VTK XML
+	// Identifiers for compressed data in VTK XML
 	zlibCompressor = "vtkZLibDataCompressor"
+	lz4Compressor  = "vtkLZ4DataCompressor"
+	lzmaCompressor = "vtkLZMADataCompressor"
+)
+
+// Algorithm identifies a compression algorithm, see CompressedWith.
+type Algorithm string
+
+// Compression algorithms supported by CompressedWith.
+const (
+	ZLib Algorithm = zlibCompressor
+	LZ4  Algorithm = lz4Compressor
+	LZMA Algorithm = lzmaCompressor
 )
 
 // Linear cell types in VTK
@@ -51,8 +63,13 @@ const (
 	Hexahedron
 	Wedge
 	Pyramid
+	PentagonalPrism
+	HexagonalPrism
 )
 
+// EmptyCell is the VTK cell type without nodes, e.g. of removed cells.
+const EmptyCell = 0
+
 // Non-linear cell types in VTK
 //
 // Refer to Fig.3 https://vtk.org/wp-content/uploads/2015/04/file-formats.pdf
@@ -63,6 +80,66 @@ const (
 	QuadraticQuad
 	QuadraticTetra
 	QuadraticHexahedron
+	QuadraticWedge
+	QuadraticPyramid
+	BiQuadraticQuad
+	TriQuadraticHexahedron
+	QuadraticLinearQuad
+	QuadraticLinearWedge
+	BiQuadraticQuadraticWedge
+	BiQuadraticQuadraticHexahedron
+	BiQuadraticTriangle
+	CubicLine
+	QuadraticPolygon
+	TriQuadraticPyramid
+)
+
+// Cell types in VTK that consist of an arbitrary set of points or faces.
+const (
+	ConvexPointSet = iota + 41
+	Polyhedron
+)
+
+// Parametric cell types in VTK, i.e. cells described by parametric functions
+// rather than interpolation of their nodes.
+const (
+	ParametricCurve = iota + 51
+	ParametricSurface
+	ParametricTriSurface
+	ParametricQuadSurface
+	ParametricTetraRegion
+	ParametricHexRegion
+)
+
+// Higher order cell types in VTK of unspecified interpolation, which precede
+// the Lagrange and Bezier cells.
+const (
+	HigherOrderEdge = iota + 60
+	HigherOrderTriangle
+	HigherOrderQuad
+	HigherOrderPolygon
+	HigherOrderTetrahedron
+	HigherOrderWedge
+	HigherOrderPyramid
+	HigherOrderHexahedron
+)
+
+// Arbitrary order Lagrange and Bezier cell types in VTK
+const (
+	LagrangeCurve = iota + 68
+	LagrangeTriangle
+	LagrangeQuadrilateral
+	LagrangeTetrahedron
+	LagrangeHexahedron
+	LagrangeWedge
+	LagrangePyramid
+	BezierCurve
+	BezierTriangle
+	BezierQuadrilateral
+	BezierTetrahedron
+	BezierHexahedron
+	BezierWedge
+	BezierPyramid
 )
 
 // header of the vtu Files
@@ -79,10 +156,37 @@ type Header struct {
 	format     string
 	compressor compressor
 
+	// significant digits of ascii floating point values, see Precision()
+	precision int
+
+	// compression settings, see CompressedLevel(), CompressionBlockSize(),
+	// and CompressionWorkers()
+	level     int
+	blockSize int
+	workers   int
+
+	// On true writes UInt64 payload headers, see HeaderType64()
+	header64 bool
+
+	// On true writes ids as Int64 instead of Int32, see IDType64()
+	idType64 bool
+
+	// On true IDType64() requested the Int64 ids, rather than FormatVersion()
+	userIDType64 bool
+
+	// On true computes the range of each array, see DataRange()
+	ranges bool
+
+	// On true encodes the appended data while writing, see Streaming()
+	streaming bool
+
+	// On true syncs saved files to disk, see Sync()
+	sync bool
+
 	// maps user's element label towards vtk's element type
 	labelType map[int]int
 
-	// On true writes Legacy (*.vtk) format
+	// On true writes Legacy (*.vtk) format, see Legacy()
 	legacy bool
 }
 
@@ -97,6 +201,8 @@ func newHeader(t string, opts ...Option) (*Header, error) {
 		ByteOrder: "LittleEndian",
 		Grid:      Grid{XMLName: xml.Name{Local: t}},
 		format:    formatBinary,
+		precision: -1,
+		level:     DefaultCompression,
 		//compressor: zlibCompression{},
 		compressor: noCompression{},
 	}
@@ -176,7 +282,12 @@ func (b bounds) String() string {
 	)
 }
 
+// MarshalXMLAttr omits empty bounds, i.e. the extents of unstructured grids
+// and poly data.
 func (b bounds) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if b == (bounds{}) {
+		return xml.Attr{}, nil
+	}
 	return xml.Attr{Name: name, Value: fmt.Sprint(b)}, nil
 }
 
@@ -193,16 +304,49 @@ type Grid struct {
 // Partition contains all vtu related data of a partition of the mesh, this
 // partition can be the complete, or a subset of, the mesh. The VTU docs
 // refer to a partition as a "Piece".
+//
+// Poly data pieces store their cells by topology in Verts, Lines, Strips,
+// and Polys instead of Cells. Their counts are omitted for all other formats,
+// while NumberOfCells is omitted for poly data, see MarshalXML.
 type partition struct {
 	XMLName        xml.Name   `xml:"Piece"`
 	Extent         bounds     `xml:"Extent,attr,omitempty"`
 	NumberOfPoints int        `xml:"NumberOfPoints,attr"`
 	NumberOfCells  int        `xml:"NumberOfCells,attr"`
+	NumberOfVerts  int        `xml:"NumberOfVerts,attr,omitempty"`
+	NumberOfLines  int        `xml:"NumberOfLines,attr,omitempty"`
+	NumberOfStrips int        `xml:"NumberOfStrips,attr,omitempty"`
+	NumberOfPolys  int        `xml:"NumberOfPolys,attr,omitempty"`
 	Points         *dataArray `xml:",omitempty"` // todo seems overly verbose?
 	Cells          *dataArray `xml:",omitempty"`
+	Verts          *dataArray `xml:",omitempty"`
+	Lines          *dataArray `xml:",omitempty"`
+	Strips         *dataArray `xml:",omitempty"`
+	Polys          *dataArray `xml:",omitempty"`
 	Coordinates    *dataArray `xml:",omitempty"`
 	PointData      *dataArray `xml:",omitempty"`
 	CellData       *dataArray `xml:",omitempty"`
+
+	// poly is true for pieces of poly data, where NumberOfCells only counts
+	// the cells of all topologies to distribute the cell data.
+	poly bool
+}
+
+// MarshalXML encodes the piece, where NumberOfCells is omitted for poly
+// data as the format does not define it.
+func (p partition) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// piece has the fields of partition without its methods
+	type piece partition
+	start.Name = xml.Name{Local: "Piece"}
+	if !p.poly {
+		return e.EncodeElement(piece(p), start)
+	}
+
+	poly := struct {
+		piece
+		NumberOfCells int `xml:"NumberOfCells,attr,omitempty"`
+	}{piece: piece(p)}
+	return e.EncodeElement(poly, start)
 }
 
 func (h *Header) NewArray() *dataArray {
@@ -241,14 +385,86 @@ func (h *Header) createArray(fieldData bool) *dataArray {
 	var enc encoder
 	switch h.format {
 	case formatAscii:
-		enc = asciier{}
+		enc = asciier{precision: h.precision}
 	case formatBinary:
 		enc = base64er{}
 	case formatRaw:
 		enc = binaryer{}
 	}
 
-	return newDataArray(enc, h.compressor, fieldData, h.Appended)
+	da := newDataArray(enc, h.compressor, fieldData, h.Appended)
+	da.header64 = h.header64
+	da.promote = h.promoteHeader
+	da.streaming = h.streaming
+	da.leadingZero = h.Version >= 2
+	da.idType64 = h.idType64
+	da.ranges = &h.ranges
+	return da
+}
+
+// dataArrays returns all dataArrays of the header that hold data.
+func (h *Header) dataArrays() []*dataArray {
+	arrays := []*dataArray{h.Grid.Data}
+	for _, p := range h.Grid.Pieces {
+		arrays = append(arrays, p.Points, p.Cells, p.Verts, p.Lines,
+			p.Strips, p.Polys, p.Coordinates, p.PointData, p.CellData)
+	}
+
+	res := arrays[:0]
+	for _, da := range arrays {
+		if da != nil {
+			res = append(res, da)
+		}
+	}
+	return res
+}
+
+// promoteHeader switches the header type to UInt64 and encodes all arrays
+// added so far again, as the header type applies to all arrays in the file.
+// Ascii arrays have no payload header, hence these are not encoded again.
+func (h *Header) promoteHeader() error {
+	if h.header64 {
+		return nil
+	}
+	h.header64 = true
+	h.setHeaderType()
+
+	if h.format == formatAscii {
+		for _, da := range h.dataArrays() {
+			da.header64 = true
+		}
+		return nil
+	}
+
+	// decoders refer to the appended data as encoded before
+	arrays := h.dataArrays()
+	decoders := make([]*xmlDecoder, len(arrays))
+	for i, da := range arrays {
+		decoders[i] = da.decoder()
+	}
+
+	if h.Appended != nil {
+		h.Appended.Data = nil
+		h.Appended.streams = nil
+		h.Appended.streamed = 0
+	}
+	for i, da := range arrays {
+		da.header64 = true
+		if err := da.reencode(decoders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setHeaderType sets the header type attribute, i.e. UInt32 by default or
+// UInt64 when requested by HeaderType64().
+func (h *Header) setHeaderType() {
+	if h.header64 {
+		h.HeaderType = "UInt64"
+		return
+	}
+	h.HeaderType = "UInt32"
 }
 
 // Set applies a set of Options to the header
@@ -268,7 +484,7 @@ func Points(xyz ...interface{}) Option {
 			return h.coordinates(xyz...)
 		case structuredGrid:
 			return h.structuredPoints(xyz...)
-		case unstructuredGrid:
+		case unstructuredGrid, polyData:
 			return h.unstructuredPoints(xyz...)
 		}
 		return nil
@@ -283,7 +499,7 @@ func Points(xyz ...interface{}) Option {
 // three coordinates, e.g. x and z. In this case, the missing set of
 // coordinates are filled with zeros.
 func (h *Header) structuredPoints(xyz ...interface{}) error {
-	if len(xyz) > 3 {
+	if len(xyz) == 0 || len(xyz) > 3 {
 		msg := "Point data should be 1,2, or 3 dimensional, got: %d"
 		return fmt.Errorf(msg, len(xyz))
 	}
@@ -299,7 +515,10 @@ func (h *Header) structuredPoints(xyz ...interface{}) error {
 
 	// Flat data vector as (x0,y0,z0,x1,y1,z1...xn,yn,zn).
 	if len(xyz) == 1 {
-		n := reflect.ValueOf(xyz[0]).Len()
+		n, err := dataLength(xyz[0])
+		if err != nil {
+			return err
+		}
 		if n != 3*lp.NumberOfPoints {
 			msg := "Wrong number of values: exp: %d, got: %d"
 			return fmt.Errorf(msg, 3*lp.NumberOfPoints, n)
@@ -324,7 +543,7 @@ func (h *Header) structuredPoints(xyz ...interface{}) error {
 // points need to be inferred from the data, there is no extent that we
 // can refer to
 func (h *Header) unstructuredPoints(xyz ...interface{}) error {
-	if len(xyz) > 3 {
+	if len(xyz) == 0 || len(xyz) > 3 {
 		msg := "Point data should be 1,2, or 3 dimensional, got: %d"
 		return fmt.Errorf(msg, len(xyz))
 	}
@@ -340,7 +559,10 @@ func (h *Header) unstructuredPoints(xyz ...interface{}) error {
 
 	// Flat data vector as (x0,y0,z0,x1,y1,z1...xn,yn,zn).
 	if len(xyz) == 1 {
-		n := reflect.ValueOf(xyz[0]).Len()
+		n, err := dataLength(xyz[0])
+		if err != nil {
+			return err
+		}
 		if n%3 > 0 {
 			msg := "Length: %d does not distribute over 3 dimension"
 			return fmt.Errorf(msg, n)
@@ -352,7 +574,10 @@ func (h *Header) unstructuredPoints(xyz ...interface{}) error {
 	// Interleave (x,y,) or (x,y,z) data. For (x,y,) a zero is inserted
 	// for the third dimension. Note: cannot distinguish the empty
 	// dimension, therefore will fill x, y, and splice z with zeros.
-	lp.NumberOfPoints = reflect.ValueOf(xyz[0]).Len()
+	lp.NumberOfPoints, err = dataLength(xyz[0])
+	if err != nil {
+		return err
+	}
 	dat, err := interleave(lp.NumberOfPoints, len(xyz), xyz...)
 	if err != nil {
 		return err
@@ -362,7 +587,7 @@ func (h *Header) unstructuredPoints(xyz ...interface{}) error {
 
 func Piece(opts ...func(p *partition) error) Option {
 	return func(h *Header) error {
-		p := new(partition)
+		p := &partition{poly: h.Type == polyData}
 		for _, opt := range opts {
 			if err := opt(p); err != nil {
 				return err
@@ -377,7 +602,7 @@ func Piece(opts ...func(p *partition) error) Option {
 // or cells, the data is written accordingly. For ambiguous cases, the
 // function returns an error. The PointData and CellData calls should then be
 // considered instead.
-func Data(name string, data interface{}) Option {
+func Data(name string, data interface{}, opts ...ArrayOption) Option {
 	return func(h *Header) error {
 
 		lp, err := h.lastPiece()
@@ -389,30 +614,36 @@ func Data(name string, data interface{}) Option {
 			return fmt.Errorf("num cells == num points, cannot infer")
 		}
 
-		n := reflect.ValueOf(data).Len()
-		if n%lp.NumberOfPoints == 0 {
-			return h.pointData(name, data)
+		n, err := dataLength(data)
+		if err != nil {
+			return err
+		}
+		if lp.NumberOfPoints > 0 && n%lp.NumberOfPoints == 0 {
+			return h.pointData(name, data, opts...)
 		}
 
-		if n%lp.NumberOfCells == 0 {
-			return h.cellData(name, data)
+		if lp.NumberOfCells > 0 && n%lp.NumberOfCells == 0 {
+			return h.cellData(name, data, opts...)
 		}
 
-		return nil
+		msg := "Data of length %d does not distribute over %d points or %d cells"
+		return fmt.Errorf(msg, n, lp.NumberOfPoints, lp.NumberOfCells)
 	}
 }
 
-// PointData writes the data to point data.
-func PointData(name string, data interface{}) Option {
+// PointData writes the data to point data. The array options, e.g.
+// ComponentNames, set additional properties of the array.
+func PointData(name string, data interface{}, opts ...ArrayOption) Option {
 	return func(h *Header) error {
-		return h.pointData(name, data)
+		return h.pointData(name, data, opts...)
 	}
 }
 
-// CellData writes the data to cell data.
-func CellData(name string, data interface{}) Option {
+// CellData writes the data to cell data. The array options, e.g.
+// ComponentNames, set additional properties of the array.
+func CellData(name string, data interface{}, opts ...ArrayOption) Option {
 	return func(h *Header) error {
-		return h.cellData(name, data)
+		return h.cellData(name, data, opts...)
 	}
 }
 
@@ -426,6 +657,10 @@ func CellData(name string, data interface{}) Option {
 // corresponding VTK element types. This map is set by SetLabelType().
 func Cells(conn, offset, labels []int) Option {
 	return func(h *Header) error {
+		if h.Type != unstructuredGrid {
+			return fmt.Errorf("Cells only apply to format %v", unstructuredGrid)
+		}
+
 		lp, err := h.lastPiece()
 		if err != nil {
 			return err
@@ -434,35 +669,139 @@ func Cells(conn, offset, labels []int) Option {
 		if lp.Cells != nil {
 			return fmt.Errorf("Connectivity already set")
 		}
-		lp.Cells = h.NewArray()
-
-		// need to assert lengths probably...
-		lp.NumberOfCells = len(labels)
 
-		if err := lp.Cells.add("connectivity", 1, conn); err != nil {
-			return err
+		if len(offset) > 0 && offset[0] == 0 {
+			// format version 1 does not require a leading zero
+			offset = offset[1:]
+		}
+		if len(offset) != len(labels) {
+			msg := "Number of offsets %d does not match number of labels %d"
+			return fmt.Errorf(msg, len(offset), len(labels))
 		}
 
-		if offset[0] == 0 {
-			// the format does not require a leading zero
-			offset = offset[1:]
+		labels, err = h.mapLabelToType(labels)
+		if err != nil {
+			return err
 		}
-		if err := lp.Cells.add("offsets", 1, offset); err != nil {
+		types, err := cellTypes(labels)
+		if err != nil {
 			return err
 		}
 
-		labels, err := h.mapLabelToType(labels)
-		if err != nil {
+		// the piece is only updated once all arrays are added
+		cells := h.NewArray()
+		if err := cells.addIDs("connectivity", conn); err != nil {
+			return err
+		}
+		if cells.leadingZero {
+			offset = append([]int{0}, offset...)
+		}
+		if err := cells.addIDs("offsets", offset); err != nil {
 			return err
 		}
-		if err := lp.Cells.add("types", 1, labels); err != nil {
+		if err := cells.add("types", 1, types); err != nil {
 			return err
 		}
 
+		lp.Cells = cells
+		lp.NumberOfCells = len(labels)
 		return nil
 	}
 }
 
+// Verts sets the vertex cells of the poly data. The vertices are represented
+// by two integer slices:
+// - conn: points to coordinates (set by Points)
+// - offsets: indicating starting point of each vertex cell in conn
+func Verts(conn, offset []int) Option {
+	return func(h *Header) error {
+		return h.polyCells("Verts", conn, offset)
+	}
+}
+
+// Lines sets the line and poly line cells of the poly data. The slices
+// follow the same convention as Verts.
+func Lines(conn, offset []int) Option {
+	return func(h *Header) error {
+		return h.polyCells("Lines", conn, offset)
+	}
+}
+
+// Strips sets the triangle strip cells of the poly data. The slices follow
+// the same convention as Verts.
+func Strips(conn, offset []int) Option {
+	return func(h *Header) error {
+		return h.polyCells("Strips", conn, offset)
+	}
+}
+
+// Polys sets the polygonal cells of the poly data, e.g. triangles, quads, or
+// arbitrary polygons. The slices follow the same convention as Verts.
+func Polys(conn, offset []int) Option {
+	return func(h *Header) error {
+		return h.polyCells("Polys", conn, offset)
+	}
+}
+
+// polyCells stores the connectivity and offsets of one of the four topology
+// groups of the poly data format. The number of cells of the group is
+// inferred from the offsets and added to the total number of cells of the
+// piece, such that cell data distributes over all groups.
+func (h *Header) polyCells(kind string, conn, offset []int) error {
+	if h.Type != polyData {
+		return fmt.Errorf("%s only apply to format %v", kind, polyData)
+	}
+
+	lp, err := h.lastPiece()
+	if err != nil {
+		return err
+	}
+
+	if len(offset) > 0 && offset[0] == 0 {
+		// format version 1 does not require a leading zero
+		offset = offset[1:]
+	}
+	if n := len(offset); n > 0 && offset[n-1] != len(conn) {
+		msg := "Last offset %d does not match connectivity length %d"
+		return fmt.Errorf(msg, offset[n-1], len(conn))
+	}
+	cells := len(offset)
+
+	var arr **dataArray
+	var num *int
+	switch kind {
+	case "Verts":
+		arr, num = &lp.Verts, &lp.NumberOfVerts
+	case "Lines":
+		arr, num = &lp.Lines, &lp.NumberOfLines
+	case "Strips":
+		arr, num = &lp.Strips, &lp.NumberOfStrips
+	case "Polys":
+		arr, num = &lp.Polys, &lp.NumberOfPolys
+	default:
+		return fmt.Errorf("Unknown poly data topology '%s'", kind)
+	}
+
+	if *arr != nil {
+		return fmt.Errorf("%s already set", kind)
+	}
+	*arr = h.NewArray()
+
+	if err := (*arr).addIDs("connectivity", conn); err != nil {
+		return err
+	}
+	if (*arr).leadingZero {
+		offset = append([]int{0}, offset...)
+	}
+	if err := (*arr).addIDs("offsets", offset); err != nil {
+		return err
+	}
+
+	*num = cells
+	lp.NumberOfCells += cells
+	return nil
+}
+
 // SetLabelType sets the labelType map in the header. The labelType is used
 // in unstructured grids to map the user's provided element labels towards
 // the internal labeling.
@@ -498,7 +837,7 @@ func (h *Header) mapLabelToType(labels []int) ([]int, error) {
 	return types, nil
 }
 
-func FieldData(name string, data interface{}) Option {
+func FieldData(name string, data interface{}, opts ...ArrayOption) Option {
 	return func(h *Header) error {
 
 		if h.Grid.Data == nil {
@@ -512,12 +851,15 @@ func FieldData(name string, data interface{}) Option {
 				msg := "Cannot cast %v to int"
 				return fmt.Errorf(msg, data)
 			}
-			return h.Grid.Data.add(name, 1, int64(tmp))
+			return h.Grid.Data.add(name, 1, int64(tmp), opts...)
 		case bool, int32, int64, float64, float32:
-			return h.Grid.Data.add(name, 1, data)
+			return h.Grid.Data.add(name, 1, data, opts...)
 		default:
-			n := reflect.ValueOf(data).Len()
-			return h.Grid.Data.add(name, n, data)
+			n, err := dataLength(data)
+			if err != nil {
+				return err
+			}
+			return h.Grid.Data.add(name, n, data, opts...)
 		}
 	}
 }
@@ -554,7 +896,10 @@ func (h *Header) coordinates(xyz ...interface{}) error {
 	for i, v := range xyz {
 
 		// length data vs num points for dimension i
-		l := reflect.ValueOf(v).Len()
+		l, err := dataLength(v)
+		if err != nil {
+			return err
+		}
 		n := h.Grid.Extent[2*i+1] - h.Grid.Extent[2*i] + 1
 
 		if l != n {
@@ -564,7 +909,7 @@ func (h *Header) coordinates(xyz ...interface{}) error {
 		}
 
 		field := fmt.Sprintf("%s_coordinates", dim[i])
-		err := lp.Coordinates.add(field, 1, v)
+		err = lp.Coordinates.add(field, 1, v)
 		if err != nil {
 			return err
 		}
@@ -584,6 +929,16 @@ func Ascii() Option {
 	}
 }
 
+// Precision sets the number of significant digits used to write floating
+// point values in ascii format. By default, a negative precision, values are
+// written using the fewest digits that read back to the exact same value.
+func Precision(digits int) Option {
+	return func(h *Header) error {
+		h.precision = digits
+		return nil
+	}
+}
+
 // The binary VTU format is actually base64 encoded to not break xml
 func Binary() Option {
 	return func(h *Header) error {
@@ -596,7 +951,7 @@ func Raw() Option {
 	return func(h *Header) error {
 		h.format = formatRaw
 		h.setAppendedData()
-		h.HeaderType = "UInt32" // combine this into an internal setting maybe?
+		h.setHeaderType()
 		return nil
 	}
 }
@@ -608,7 +963,57 @@ func Appended() Option {
 			return fmt.Errorf(msg, h.format)
 		}
 		h.setAppendedData()
-		h.HeaderType = "UInt32"
+		h.setHeaderType()
+		return nil
+	}
+}
+
+// HeaderType64 writes the payload headers, i.e. the sizes of the data, as
+// UInt64 instead of UInt32. This is required for arrays exceeding 4 GiB.
+// The header type is promoted automatically when an array requires so.
+func HeaderType64() Option {
+	return func(h *Header) error {
+		return h.promoteHeader()
+	}
+}
+
+// FormatVersion sets the version of the VTK XML format. Version 1.0, the
+// default, is read by all VTK versions. Version 2.2 matches the files written
+// by VTK 9, where the offsets of the cells include a leading zero, ids are
+// written as Int64, see IDType64(), and the faces of polyhedra are only
+// written in the layout of VTK 9. The version is required to be set before
+// adding points or cells.
+func FormatVersion(major, minor int) Option {
+	return func(h *Header) error {
+		if len(h.Grid.Pieces) > 0 {
+			return fmt.Errorf("Format version should be set before adding points or cells")
+		}
+		switch {
+		case major == 1 && minor == 0:
+			h.Version = 1.0
+			h.idType64 = h.userIDType64
+		case major == 2 && minor == 2:
+			h.Version = 2.2
+			h.idType64 = true
+		default:
+			return fmt.Errorf("Unsupported format version %d.%d", major, minor)
+		}
+		return nil
+	}
+}
+
+// IDType64 writes the ids of the cells, i.e. the connectivity and offsets,
+// as Int64 instead of Int32, matching VTK's 64-bit vtkIdType. Without this
+// option, only the arrays holding ids beyond the range of Int32 are written
+// as Int64. The id type is required to be set before adding points or
+// cells.
+func IDType64() Option {
+	return func(h *Header) error {
+		if len(h.Grid.Pieces) > 0 {
+			return fmt.Errorf("ID type should be set before adding points or cells")
+		}
+		h.idType64 = true
+		h.userIDType64 = true
 		return nil
 	}
 }
@@ -620,22 +1025,89 @@ func Compressed() Option {
 
 // CompressedLevel assigns the compressor using a specific compression level.
 // Constants are provided: NoCompression, BestSpeed, BestCompression,
-// DefaultCompression, and HuffmanOnly.
+// DefaultCompression, and HuffmanOnly. The level is ignored by LZMA, except
+// for NoCompression.
 func CompressedLevel(level int) Option {
 	return func(h *Header) error {
-		h.HeaderType = "UInt32"
+		h.setHeaderType()
 
+		// keep the algorithm when selected by CompressedWith
+		if h.Compression == "" {
+			h.Compression = zlibCompressor
+		}
 		if level == NoCompression {
-			h.compressor = noCompression{}
-			return nil
+			h.Compression = ""
+		}
+		h.level = level
+		h.setCompressor()
+		return nil
+	}
+}
+
+// CompressedWith assigns the compressor using the provided algorithm, i.e.
+// ZLib, LZ4, or LZMA. The compression level of ZLib and LZ4 is set by
+// CompressedLevel, which defaults to DefaultCompression. LZMA ignores the
+// compression level.
+func CompressedWith(a Algorithm) Option {
+	return func(h *Header) error {
+		switch a {
+		case ZLib, LZ4, LZMA:
+		default:
+			return fmt.Errorf("Unknown compression algorithm '%s'", a)
 		}
 
-		h.Compression = zlibCompressor // todo update names
-		h.compressor = zlibCompression{level: level}
+		h.setHeaderType()
+		h.Compression = string(a)
+		h.setCompressor()
 		return nil
 	}
 }
 
+// CompressionBlockSize sets the number of uncompressed bytes per compressed
+// block. Each array is split into blocks of this size, which are compressed
+// individually. By default DefaultBlockSize is used, similar to VTK.
+func CompressionBlockSize(n int) Option {
+	return func(h *Header) error {
+		if n <= 0 {
+			return fmt.Errorf("Compression block size must be positive, got: %d", n)
+		}
+		h.blockSize = n
+		h.setCompressor()
+		return nil
+	}
+}
+
+// CompressionWorkers sets the number of blocks that are compressed
+// concurrently. The compressed blocks are written in order, such that the
+// output equals the output of a single worker, which is the default.
+func CompressionWorkers(n int) Option {
+	return func(h *Header) error {
+		if n <= 0 {
+			return fmt.Errorf("Compression workers must be positive, got: %d", n)
+		}
+		h.workers = n
+		h.setCompressor()
+		return nil
+	}
+}
+
+// setCompressor assigns the compressor matching the compression settings.
+func (h *Header) setCompressor() {
+	switch h.Compression {
+	case zlibCompressor:
+		h.compressor = zlibCompression{level: h.level,
+			blockSize: h.blockSize, workers: h.workers}
+	case lz4Compressor:
+		h.compressor = lz4Compression{level: h.level,
+			blockSize: h.blockSize, workers: h.workers}
+	case lzmaCompressor:
+		h.compressor = lzmaCompression{blockSize: h.blockSize,
+			workers: h.workers}
+	default:
+		h.compressor = noCompression{}
+	}
+}
+
 // WholeExtent sets the extent of the Image, Rectilinear, or Structured grids.
 // The extent requires a lower and upper value for each dimension, where a
 // single dimension can be left empty, e.g. x1 - x0 == 0.
@@ -654,7 +1126,7 @@ func WholeExtent(x0, x1, y0, y1, z0, z1 int) Option {
 // Origin sets the origin of the VTK image, rectilinear, and structured grids.
 func Origin(x, y, z float64) Option {
 	return func(h *Header) error {
-		h.Grid.Origin = fmt.Sprintf("%f %f %f", x, y, z)
+		h.Grid.Origin = formatTriple(x, y, z)
 		return nil
 	}
 }
@@ -662,11 +1134,18 @@ func Origin(x, y, z float64) Option {
 // Spacing sets the spacing in x, y, z direction of the VTK image grids.
 func Spacing(dx, dy, dz float64) Option {
 	return func(h *Header) error {
-		h.Grid.Spacing = fmt.Sprintf("%f %f %f", dx, dy, dz)
+		h.Grid.Spacing = formatTriple(dx, dy, dz)
 		return nil
 	}
 }
 
+// formatTriple formats three values separated by a space using the shortest
+// representation that reads back to the exact same values.
+func formatTriple(x, y, z float64) string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	return f(x) + " " + f(y) + " " + f(z)
+}
+
 // Extent sets the part of the domain given in the current partition. This
 // should be within WholeExtent.
 func Extent(x0, x1, y0, y1, z0, z1 int) func(p *partition) error {
@@ -705,19 +1184,18 @@ func Unstructured(opts ...Option) (*Header, error) {
 	return newHeader(unstructuredGrid, opts...)
 }
 
-// Save opens a file and writes the xml
+// Create file with poly data format
+func PolyData(opts ...Option) (*Header, error) {
+	return newHeader(polyData, opts...)
+}
+
+// Save writes the xml to file, see saveFile. The file extension matching the
+// header is appended if filename has none.
 func (h *Header) Save(filename string) error {
-	// append extension if filename has none
 	if filepath.Ext(filename) == "" {
-		filename += h.FileExtension()
-	}
-
-	f, err := os.Create(filename)
-	defer f.Close()
-	if err != nil {
-		return err
+		filename += "." + h.FileExtension()
 	}
-	return h.Write(bufio.NewWriter(f))
+	return saveFile(filename, h.sync, h.Write)
 }
 
 // Encodes the xml towards a io.Writer. Writes a xml header (i.e.
@@ -735,6 +1213,13 @@ func (h *Header) Write(w io.Writer) error {
 		}
 	}
 
+	if h.legacy {
+		return h.writeLegacy(w)
+	}
+	if h.streaming {
+		return h.writeStreamed(w)
+	}
+
 	if h.format != formatRaw {
 		_, err := w.Write([]byte(xml.Header))
 		if err != nil {
@@ -754,57 +1239,87 @@ func (h *Header) WriteClose(w io.WriteCloser) error {
 
 // pointData is the internal routine to write data along points. The function
 // returns an error if the data does not distribute over the number of points.
-func (h *Header) pointData(name string, data interface{}) error {
+func (h *Header) pointData(name string, data interface{},
+	opts ...ArrayOption) error {
 	lp, err := h.lastPiece()
 	if err != nil {
 		return err
 	}
 
-	if lp.PointData == nil {
-		lp.PointData = h.NewArray()
+	n, err := dataLength(data)
+	if err != nil {
+		return err
+	}
+	if lp.NumberOfPoints == 0 {
+		return fmt.Errorf("Point data '%s' requires points to be set", name)
 	}
-
-	n := reflect.ValueOf(data).Len()
 	if n%lp.NumberOfPoints > 0 {
 		return fmt.Errorf("Data does not distribute over points")
 	}
 
+	if lp.PointData == nil {
+		lp.PointData = h.NewArray()
+	}
+
 	n /= lp.NumberOfPoints
-	return lp.PointData.add(name, n, data)
+	return lp.PointData.add(name, n, data, opts...)
 }
 
 // cellData is the internal routine to write data along cells. The function
 // returns an error if the data does not distribute over the number of cells.
-func (h *Header) cellData(name string, data interface{}) error {
+func (h *Header) cellData(name string, data interface{},
+	opts ...ArrayOption) error {
 	lp, err := h.lastPiece()
 	if err != nil {
 		return err
 	}
 
-	if lp.CellData == nil {
-		lp.CellData = h.NewArray()
+	n, err := dataLength(data)
+	if err != nil {
+		return err
+	}
+	if lp.NumberOfCells == 0 {
+		return fmt.Errorf("Cell data '%s' requires cells to be set", name)
 	}
-
-	n := reflect.ValueOf(data).Len()
 	if n%lp.NumberOfCells > 0 {
 		return fmt.Errorf("Data does not distribute over cells, len %v got %v",
 			lp.NumberOfCells, n)
 	}
 
+	if lp.CellData == nil {
+		lp.CellData = h.NewArray()
+	}
+
 	n /= lp.NumberOfCells
-	return lp.CellData.add(name, n, data)
+	return lp.CellData.add(name, n, data, opts...)
+}
+
+// dataLength returns the length of the data, which is required to be a
+// slice. Any other data returns ErrUnsupportedType.
+func dataLength(data interface{}) (int, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("%w: %T is not a slice", ErrUnsupportedType, data)
+	}
+	return v.Len(), nil
 }
 
 func (h *Header) FileExtension() string {
+	if h.legacy {
+		return "vtk"
+	}
+
 	switch h.Type {
 	case imageData:
 		return "vti"
 	case rectilinearGrid:
-		return "vtu"
+		return "vtr"
 	case structuredGrid:
 		return "vts"
 	case unstructuredGrid:
 		return "vtu"
+	case polyData:
+		return "vtp"
 	}
 	return ""
 }
@@ -856,7 +1371,11 @@ func interleave(np, zero int, xyz ...interface{}) (interface{}, error) {
 	// ensure all components have equal length
 	n := make([]int, len(xyz))
 	for i, v := range xyz {
-		n[i] = reflect.ValueOf(v).Len()
+		l, err := dataLength(v)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = l
 	}
 	for _, v := range n {
 		if v != np {
@@ -901,7 +1420,7 @@ func interleave(np, zero int, xyz ...interface{}) (interface{}, error) {
 		}
 		return res, nil
 	default:
-		msg := "interleave is not implemented for type '%T'"
-		return nil, fmt.Errorf(msg, xyz[0])
+		msg := "%w: interleave is not implemented for type '%T'"
+		return nil, fmt.Errorf(msg, ErrUnsupportedType, xyz[0])
 	}
 }
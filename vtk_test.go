@@ -1,11 +1,34 @@
 package govtk
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
+// newTetra returns a single tetrahedron with point and cell data.
+func newTetra(t *testing.T, opts ...Option) *Header {
+	vtu, err := Unstructured(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coords := []float64{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1}
+	err = vtu.Add(
+		Points(coords),
+		Cells([]int{0, 1, 2, 3}, []int{0, 4}, []int{Tetra}),
+		PointData("temperature", []float64{1, 2, 3, 4}),
+		CellData("velocity", []float64{1, 0, 0}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vtu
+}
+
 func TestExtentPresent(t *testing.T) {
 	img, err := Image()
 	if err != nil {
@@ -59,6 +82,40 @@ func TestFieldData(t *testing.T) {
 	}
 }
 
+// single values are written as field data of a single tuple in ascii format
+func TestFieldDataAscii(t *testing.T) {
+	img, err := Image(WholeExtent(0, 1, 0, 1, 0, 1), Ascii())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := img.Add(FieldData("time", 1.5), FieldData("step", 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := img.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time, err := r.FieldArray("time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []float64{1.5}; !reflect.DeepEqual(time, exp) {
+		t.Errorf("Wrong time: got %v, exp %v", time, exp)
+	}
+	step, err := r.FieldArray("step")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []int64{3}; !reflect.DeepEqual(step, exp) {
+		t.Errorf("Wrong step: got %v, exp %v", step, exp)
+	}
+}
+
 func TestPreventDuplicateFieldNames(t *testing.T) {
 	vtu, err := Image(WholeExtent(0, 1, 0, 1, 0, 1))
 	if err != nil {
@@ -74,11 +131,81 @@ func TestPreventDuplicateFieldNames(t *testing.T) {
 
 	// expect error for adding the same field
 	err = vtu.Add(Data(str, []int{1}))
-	if err == nil {
+	if !errors.Is(err, ErrDuplicateName) {
 		t.Errorf("no duplicates: %v", err)
 	}
 }
 
+func TestOriginSpacing(t *testing.T) {
+	img, err := Image(Origin(-1e-7, 0.1, 25e6), Spacing(1, 0.5, 1.0/3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "-1e-07 0.1 2.5e+07"; img.Grid.Origin != exp {
+		t.Errorf("Origin: got %q exp %q", img.Grid.Origin, exp)
+	}
+	if exp := "1 0.5 0.3333333333333333"; img.Grid.Spacing != exp {
+		t.Errorf("Spacing: got %q exp %q", img.Grid.Spacing, exp)
+	}
+}
+
+func TestUnsupportedData(t *testing.T) {
+	vti, err := Image(WholeExtent(0, 1, 0, 1, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = vti.Add(PointData("text", make([]string, 8)))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got: %v", err)
+	}
+	err = vti.Add(CellData("complex", []complex128{1}))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got: %v", err)
+	}
+
+	// single values are not distributed over points or cells
+	for _, opt := range []Option{PointData("x", 1.0), CellData("x", 1.0),
+		Data("x", 1.0), FieldData("x", "text")} {
+		if err := vti.Add(opt); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("expected ErrUnsupportedType, got: %v", err)
+		}
+	}
+
+	// points and coordinates are required to be slices
+	ext := WholeExtent(0, 1, 0, 1, 0, 1)
+	for _, newGrid := range []func(...Option) (*Header, error){
+		Unstructured, Structured, Rectilinear} {
+		for _, xyz := range [][]interface{}{{1.0}, {1.0, 2.0},
+			{[]float64{0, 1}, 2.0, 3.0}} {
+			h, err := newGrid(ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = h.Add(Points(xyz...))
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("expected ErrUnsupportedType, got: %v", err)
+			}
+		}
+	}
+}
+
+func TestDataWithoutPointsCells(t *testing.T) {
+	for _, opt := range []Option{
+		PointData("p", []float64{1, 2}),
+		CellData("c", []float64{1, 2}),
+		Data("d", []float64{1, 2}),
+	} {
+		vtu, err := Unstructured()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vtu.Add(opt); err == nil {
+			t.Errorf("Expected error for data without points or cells")
+		}
+	}
+}
+
 func TestNumCellsPoints(t *testing.T) {
 	type pair struct {
 		b      bounds
@@ -228,6 +355,70 @@ func TestCompressionLevels(t *testing.T) {
 	}
 }
 
+func TestCompressedWith(t *testing.T) {
+	// the level is kept independent of the option order
+	for _, opts := range [][]Option{
+		{CompressedWith(LZ4), CompressedLevel(BestCompression)},
+		{CompressedLevel(BestCompression), CompressedWith(LZ4)},
+	} {
+		vti, err := Image(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, ok := vti.compressor.(lz4Compression)
+		if !ok || c.level != BestCompression {
+			t.Errorf("Wrong compressor: %#v", vti.compressor)
+		}
+		if vti.Compression != "vtkLZ4DataCompressor" {
+			t.Errorf("Wrong compressor attribute: %v", vti.Compression)
+		}
+	}
+
+	vti, _ := Image(CompressedWith(ZLib))
+	if c, ok := vti.compressor.(zlibCompression); !ok || c.level != DefaultCompression {
+		t.Errorf("Wrong compressor: %#v", vti.compressor)
+	}
+
+	if _, err := Image(CompressedWith("snappy")); err == nil {
+		t.Errorf("Expected error for unknown compression algorithm")
+	}
+}
+
+func TestCompressionBlockSize(t *testing.T) {
+	// block size is kept independent of the option order
+	for _, opts := range [][]Option{
+		{CompressionBlockSize(64), Compressed()},
+		{Compressed(), CompressionBlockSize(64)},
+	} {
+		vti, err := Image(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, ok := vti.compressor.(zlibCompression)
+		if !ok || c.blockSize != 64 || c.level != DefaultCompression {
+			t.Errorf("Wrong compressor: %#v", vti.compressor)
+		}
+	}
+
+	if _, err := Image(CompressionBlockSize(0)); err == nil {
+		t.Errorf("Expected error for zero block size")
+	}
+}
+
+func TestCompressionWorkersOption(t *testing.T) {
+	vti, err := Image(CompressionWorkers(4), CompressedWith(LZ4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := vti.compressor.(lz4Compression); !ok || c.workers != 4 {
+		t.Errorf("Wrong compressor: %#v", vti.compressor)
+	}
+
+	if _, err := Image(CompressionWorkers(0)); err == nil {
+		t.Errorf("Expected error for zero workers")
+	}
+}
+
 // Ensure image extent is written as expected and fails on wrong inputs.
 func TestImageExtent(t *testing.T) {
 	type pair struct {
@@ -501,3 +692,193 @@ func TestUnstructured(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestPolyData(t *testing.T) {
+	coords := []float64{
+		0.0, 0.0, 0.0,
+		1.0, 0.0, 0.0,
+		1.0, 1.0, 0.0,
+		0.0, 1.0, 0.0}
+
+	vtp, err := PolyData(Ascii())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := vtp.Add(Points(coords)); err != nil {
+		t.Error(err)
+	}
+	if err := vtp.Add(Verts([]int{0, 1}, []int{0, 1, 2})); err != nil {
+		t.Error(err)
+	}
+	if err := vtp.Add(Lines([]int{0, 1, 2, 3}, []int{0, 4})); err != nil {
+		t.Error(err)
+	}
+	if err := vtp.Add(Polys([]int{0, 1, 2, 0, 2, 3}, []int{0, 3, 6})); err != nil {
+		t.Error(err)
+	}
+
+	// duplicate topology and mismatching offsets should fail
+	if err := vtp.Add(Polys([]int{0, 1, 2}, []int{0, 3})); err == nil {
+		t.Error("Duplicate Polys should return error")
+	}
+	if err := vtp.Add(Strips([]int{0, 1, 2}, []int{0, 4})); err == nil {
+		t.Error("Offsets beyond connectivity should return error")
+	}
+
+	lp := vtp.Grid.Pieces[0]
+	if lp.NumberOfVerts != 2 || lp.NumberOfLines != 1 || lp.NumberOfPolys != 2 {
+		t.Errorf("Wrong topology counts: %d %d %d",
+			lp.NumberOfVerts, lp.NumberOfLines, lp.NumberOfPolys)
+	}
+	if lp.NumberOfCells != 5 {
+		t.Errorf("Wrong number of cells: got %d, exp %d", lp.NumberOfCells, 5)
+	}
+
+	// cell data distributes over all topology groups
+	if err := vtp.Add(CellData("id", []float64{1, 2, 3, 4, 5})); err != nil {
+		t.Error(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := vtp.Write(buf); err != nil {
+		t.Error(err)
+	}
+	for _, s := range []string{"<PolyData>", `NumberOfVerts="2"`,
+		`NumberOfPolys="2"`, "<Verts>", "<Lines>", "<Polys>"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Missing %s in poly data output", s)
+		}
+	}
+	if strings.Contains(buf.String(), "<Strips>") {
+		t.Errorf("Unset Strips should be omitted")
+	}
+	if strings.Contains(buf.String(), "NumberOfCells") {
+		t.Errorf("NumberOfCells should be omitted for poly data")
+	}
+	if vtp.FileExtension() != "vtp" {
+		t.Errorf("Wrong extension: got %v, exp %v", vtp.FileExtension(), "vtp")
+	}
+
+	// topology only applies to poly data
+	vtu, _ := Unstructured()
+	if err := vtu.Add(Polys([]int{0, 1, 2}, []int{0, 3})); err == nil {
+		t.Error("Polys on unstructured grid should return error")
+	}
+	if err := vtp.Add(Cells([]int{0, 1, 2}, []int{0, 3}, []int{Triangle})); err == nil {
+		t.Error("Cells on poly data should return error")
+	}
+}
+
+func TestFormatVersion(t *testing.T) {
+	vtu := newTetra(t, FormatVersion(2, 2))
+	if err := vtu.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// version 2.2 writes Int64 ids including a leading zero
+	offsets, _ := vtu.Grid.Pieces[0].Cells.values("offsets")
+	if exp := []int64{0, 4}; !reflect.DeepEqual(offsets, exp) {
+		t.Errorf("Wrong offsets: got %v, exp %v", offsets, exp)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := vtu.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `version="2.2"`) {
+		t.Errorf("Missing version 2.2 in output")
+	}
+
+	// the leading zero is not duplicated when reading
+	r, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offset, _, err := r.Connectivity(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []int{0, 4}; !reflect.DeepEqual(offset, exp) {
+		t.Errorf("Wrong offsets: got %v, exp %v", offset, exp)
+	}
+
+	// poly data offsets include the leading zero
+	vtp, err := PolyData(FormatVersion(2, 2), Points(make([]float64, 9)),
+		Polys([]int{0, 1, 2}, []int{3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets, _ = vtp.Grid.Pieces[0].Polys.values("offsets")
+	if exp := []int64{0, 3}; !reflect.DeepEqual(offsets, exp) {
+		t.Errorf("Wrong offsets: got %v, exp %v", offsets, exp)
+	}
+	if n := vtp.Grid.Pieces[0].NumberOfPolys; n != 1 {
+		t.Errorf("Wrong number of polys: got %d, exp %d", n, 1)
+	}
+
+	// polyhedra only hold the faces in the layout of VTK 9
+	cells := newPolyhedra(t, FormatVersion(2, 2)).Grid.Pieces[0].Cells
+	if cells.contains("faces") || !cells.contains("face_connectivity") {
+		t.Errorf("Wrong face arrays: %v", cells.fieldNames())
+	}
+
+	if _, err := Unstructured(FormatVersion(3, 0)); err == nil {
+		t.Errorf("Expected error for unsupported version")
+	}
+	if err := vtu.Add(FormatVersion(1, 0)); err == nil {
+		t.Errorf("Expected error for setting version after adding points")
+	}
+}
+
+func TestIDType(t *testing.T) {
+	// only arrays holding ids beyond Int32 are promoted
+	tests := []struct {
+		opts         []Option
+		conn         []int
+		connectivity string
+		offsets      string
+	}{
+		{nil, []int{0, 1, 2, 3}, "Int32", "Int32"},
+		{[]Option{IDType64()}, []int{0, 1, 2, 3}, "Int64", "Int64"},
+		{nil, []int{0, 1, 2, 1 << 31}, "Int64", "Int32"},
+
+		// reverting to version 1.0 keeps only explicitly requested Int64 ids
+		{[]Option{FormatVersion(2, 2), FormatVersion(1, 0)},
+			[]int{0, 1, 2, 3}, "Int32", "Int32"},
+		{[]Option{IDType64(), FormatVersion(2, 2), FormatVersion(1, 0)},
+			[]int{0, 1, 2, 3}, "Int64", "Int64"},
+	}
+	for _, test := range tests {
+		vtu, err := Unstructured(test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vtu.Add(Cells(test.conn, []int{0, 4}, []int{Tetra})); err != nil {
+			t.Fatal(err)
+		}
+
+		cells := vtu.Grid.Pieces[0].Cells
+		exp := map[string]string{
+			"connectivity": test.connectivity,
+			"offsets":      test.offsets,
+			"types":        "UInt8",
+		}
+		for name, dtype := range exp {
+			if got := cells.lookup(name).Type; got != dtype {
+				t.Errorf("Wrong type of %s: got %s, exp %s", name, got, dtype)
+			}
+		}
+	}
+
+	vtu, _ := Unstructured()
+	if err := vtu.Add(Cells([]int{0}, []int{0, 1}, []int{300})); err == nil {
+		t.Errorf("Expected error for cell type beyond UInt8")
+	}
+	if lp := vtu.Grid.Pieces[0]; lp.Cells != nil || lp.NumberOfCells != 0 {
+		t.Errorf("Piece modified by failing cells: %d cells", lp.NumberOfCells)
+	}
+	if err := vtu.Add(IDType64()); err == nil {
+		t.Errorf("Expected error for setting id type after adding cells")
+	}
+}